@@ -0,0 +1,131 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+const (
+	MIMEJSON              = "application/json"
+	MIMEXML               = "application/xml"
+	MIMEXML2              = "text/xml"
+	MIMEPOSTForm          = "application/x-www-form-urlencoded"
+	MIMEMultipartPOSTForm = "multipart/form-data"
+)
+
+const defaultMultipartMemory = 32 << 20
+
+var ErrUnsupportedContentType = errors.New("unsupported content type")
+
+// Binder decode the request of ctx into obj
+type Binder interface {
+	Name() string
+	Bind(ctx *Context, obj interface{}) error
+}
+
+var (
+	binders     = map[string]Binder{}
+	bindersLock sync.RWMutex
+)
+
+func init() {
+	RegisterBinder(MIMEPOSTForm, formBinder{})
+	RegisterBinder(MIMEMultipartPOSTForm, multipartBinder{})
+}
+
+// RegisterBinder register b for the content type, replacing any existing binder
+func RegisterBinder(contentType string, b Binder) {
+	bindersLock.Lock()
+	defer bindersLock.Unlock()
+	binders[strings.ToLower(contentType)] = b
+}
+
+// LookupBinder find the binder registered for the content type, parameters such as charset are ignored
+func LookupBinder(contentType string) (Binder, bool) {
+	if contentType == "" {
+		return formBinder{}, true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, false
+	}
+	bindersLock.RLock()
+	defer bindersLock.RUnlock()
+	b, ok := binders[mediaType]
+	return b, ok
+}
+
+// Bind is an alias of ShouldBind
+func (ctx *Context) Bind(obj interface{}) error {
+	return ctx.ShouldBind(obj)
+}
+
+// ShouldBind decode the request into obj with the binder chosen by Content-Type
+func (ctx *Context) ShouldBind(obj interface{}) error {
+	if ctx.request == nil {
+		return errors.New("ctx.request empty")
+	}
+	contentType := ctx.request.Header.Get("Content-Type")
+	b, ok := LookupBinder(contentType)
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnsupportedContentType, contentType)
+	}
+	return b.Bind(ctx, obj)
+}
+
+// MustBind works like ShouldBind, and respond 400 with the error when binding fails; malformed
+// `validate` tags are left to the error handler
+func (ctx *Context) MustBind(obj interface{}) error {
+	if err := ctx.ShouldBind(obj); err != nil {
+		var verrs ValidationErrors
+		if errors.As(err, &verrs) {
+			ctx.SetStatus(http.StatusBadRequest).JSON(verrs.Translate(ctx.Language()))
+			return err
+		}
+		if errors.Is(err, ErrInvalidValidateTag) {
+			// 错误的 tag 不是客户端的问题，交给错误处理响应 500
+			ctx.Error(err)
+			return err
+		}
+		ctx.SetStatus(errorStatus(err)).JSON(err.Error())
+		return err
+	}
+	return nil
+}
+
+type formBinder struct{}
+
+func (formBinder) Name() string {
+	return "form"
+}
+
+func (formBinder) Bind(ctx *Context, obj interface{}) error {
+	if err := ctx.parseForm(); err != nil {
+		return err
+	}
+	return mappingValidate(obj, formValuesSource(ctx.request.Form))
+}
+
+type multipartBinder struct{}
+
+func (multipartBinder) Name() string {
+	return "multipart/form-data"
+}
+
+func (multipartBinder) Bind(ctx *Context, obj interface{}) error {
+	if err := ctx.parseForm(); err != nil {
+		return err
+	}
+	return mappingValidate(obj, formValuesSource(ctx.request.Form))
+}
+
+// formValuesSource bind untagged fields by their name, like the form binders always did
+func formValuesSource(values map[string][]string) tagSource {
+	src := mapSource(tagForm, values)
+	src.byName = true
+	return src
+}
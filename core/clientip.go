@@ -0,0 +1,132 @@
+package core
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// SetTrustedProxies set the proxies whose X-Forwarded-For, Forwarded and X-Real-IP headers are
+// believed, each entry is a CIDR such as 10.0.0.0/8 or a single IP; nil trusts no proxy
+func (c *Core) SetTrustedProxies(proxies []string) error {
+	nets, err := parseCIDRs(proxies)
+	if err != nil {
+		return err
+	}
+	c.trustedProxies = nets
+	return nil
+}
+
+func parseCIDRs(entries []string) ([]*net.IPNet, error) {
+	nets := make([]*net.IPNet, 0, len(entries))
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if !strings.Contains(entry, "/") {
+			ip := net.ParseIP(entry)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid proxy address: %s", entry)
+			}
+			bits := 128
+			if ip4 := ip.To4(); ip4 != nil {
+				ip, bits = ip4, 32
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, ipNet, err := net.ParseCIDR(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy cidr: %s", entry)
+		}
+		nets = append(nets, ipNet)
+	}
+	return nets, nil
+}
+
+// containsIP report whether ip is in one of nets, used for trusted proxies and PROXY protocol peers
+func containsIP(nets []*net.IPNet, ip net.IP) bool {
+	for _, n := range nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// ClientIP resolve the client address, the forwarding headers are only used when the peer is a
+// trusted proxy, and the hops are walked right to left until the first untrusted one
+func (ctx *Context) ClientIP() string {
+	if ctx.request == nil {
+		return ""
+	}
+	r := ctx.request
+
+	remote := parseHostIP(r.RemoteAddr)
+	if remote == nil {
+		return ""
+	}
+	if !containsIP(ctx.trustedProxies, remote) {
+		return remote.String()
+	}
+
+	if forwarded := r.Header.Values("Forwarded"); len(forwarded) > 0 {
+		return ctx.walkHops(remote, parseForwarded(forwarded)).String()
+	}
+	if xff := r.Header.Values("X-Forwarded-For"); len(xff) > 0 {
+		var hops []string
+		for _, line := range xff {
+			hops = append(hops, strings.Split(line, ",")...)
+		}
+		return ctx.walkHops(remote, hops).String()
+	}
+	if ip := net.ParseIP(strings.TrimSpace(r.Header.Get("X-Real-IP"))); ip != nil {
+		return ip.String()
+	}
+	return remote.String()
+}
+
+// walkHops return the rightmost untrusted hop, or the leftmost hop when all of them are trusted;
+// an unparsable or "unknown" hop hides the real client, so the remote address is returned rather
+// than the trusted proxy next to it
+func (ctx *Context) walkHops(remote net.IP, hops []string) net.IP {
+	client := remote
+	for i := len(hops) - 1; i >= 0; i-- {
+		ip := parseHostIP(hops[i])
+		if ip == nil {
+			return remote
+		}
+		client = ip
+		if !containsIP(ctx.trustedProxies, ip) {
+			return client
+		}
+	}
+	return client
+}
+
+// parseForwarded extract the for= values of RFC 7239 Forwarded headers in order
+func parseForwarded(lines []string) []string {
+	var hops []string
+	for _, line := range lines {
+		for _, element := range strings.Split(line, ",") {
+			for _, pair := range strings.Split(element, ";") {
+				kv := strings.SplitN(strings.TrimSpace(pair), "=", 2)
+				if len(kv) != 2 || !strings.EqualFold(kv[0], "for") {
+					continue
+				}
+				hops = append(hops, strings.Trim(kv[1], `"`))
+			}
+		}
+	}
+	return hops
+}
+
+// parseHostIP parse "ip", "ip:port", "[ipv6]" or "[ipv6]:port"
+func parseHostIP(s string) net.IP {
+	s = strings.TrimSpace(s)
+	if ip := net.ParseIP(s); ip != nil {
+		return ip
+	}
+	if host, _, err := net.SplitHostPort(s); err == nil {
+		return net.ParseIP(host)
+	}
+	return net.ParseIP(strings.TrimSuffix(strings.TrimPrefix(s, "["), "]"))
+}
@@ -0,0 +1,53 @@
+package core
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	c := New()
+	if err := c.SetTrustedProxies([]string{"10.0.0.0/8", "2001:db8::1"}); err != nil {
+		t.Fatal(err)
+	}
+	c.Get("/ip", func(ctx *Context) error {
+		ctx.Text(ctx.ClientIP())
+		return nil
+	})
+
+	cases := []struct {
+		name    string
+		remote  string
+		headers map[string]string
+		want    string
+	}{
+		{name: "untrusted peer ignores headers", remote: "203.0.113.5:1234", headers: map[string]string{"X-Forwarded-For": "198.51.100.1"}, want: "203.0.113.5"},
+		{name: "trusted peer without headers", remote: "10.0.0.1:1234", want: "10.0.0.1"},
+		{name: "x-forwarded-for", remote: "10.0.0.1:1234", headers: map[string]string{"X-Forwarded-For": "198.51.100.1"}, want: "198.51.100.1"},
+		{name: "spoofed left hops are skipped", remote: "10.0.0.1:1234", headers: map[string]string{"X-Forwarded-For": "1.1.1.1, 198.51.100.1, 10.0.0.2"}, want: "198.51.100.1"},
+		{name: "all hops trusted", remote: "10.0.0.1:1234", headers: map[string]string{"X-Forwarded-For": "10.0.0.3, 10.0.0.2"}, want: "10.0.0.3"},
+		{name: "unparsable hop falls back to the peer", remote: "10.0.0.1:1234", headers: map[string]string{"X-Forwarded-For": "198.51.100.1, garbage, 10.0.0.2"}, want: "10.0.0.1"},
+		{name: "forwarded", remote: "10.0.0.1:1234", headers: map[string]string{"Forwarded": `for="[2001:db8::7]:4711";proto=https, for=10.0.0.2`}, want: "2001:db8::7"},
+		{name: "forwarded unknown falls back to the peer", remote: "10.0.0.1:1234", headers: map[string]string{"Forwarded": "for=unknown, for=10.0.0.2"}, want: "10.0.0.1"},
+		{name: "forwarded wins over x-forwarded-for", remote: "10.0.0.1:1234", headers: map[string]string{"Forwarded": "for=198.51.100.1", "X-Forwarded-For": "198.51.100.2"}, want: "198.51.100.1"},
+		{name: "x-real-ip", remote: "[2001:db8::1]:1234", headers: map[string]string{"X-Real-IP": "198.51.100.1"}, want: "198.51.100.1"},
+		{name: "bad x-real-ip", remote: "10.0.0.1:1234", headers: map[string]string{"X-Real-IP": "nope"}, want: "10.0.0.1"},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/ip", nil)
+			r.RemoteAddr = tc.remote
+			for k, v := range tc.headers {
+				r.Header.Set(k, v)
+			}
+			w := httptest.NewRecorder()
+			c.ServeHTTP(w, r)
+			if got := w.Body.String(); got != tc.want {
+				t.Fatalf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,203 @@
+package core
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"mime"
+	"strings"
+	"sync"
+
+	"google.golang.org/protobuf/proto"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	MIMEYAML     = "application/x-yaml"
+	MIMEYAML2    = "application/yaml"
+	MIMEMsgPack  = "application/msgpack"
+	MIMEMsgPack2 = "application/x-msgpack"
+	MIMEProtoBuf = "application/x-protobuf"
+	MIMECSV      = "text/csv"
+)
+
+// Codec encode response bodies and decode request bodies of one content type
+type Codec interface {
+	Name() string
+	// ContentType is sent with rendered responses
+	ContentType() string
+	Marshal(obj interface{}) ([]byte, error)
+	Unmarshal(data []byte, obj interface{}) error
+}
+
+var (
+	JSONCodec     Codec = jsonCodec{}
+	XMLCodec      Codec = xmlCodec{}
+	YAMLCodec     Codec = yamlCodec{}
+	MsgPackCodec  Codec = msgPackCodec{}
+	ProtoBufCodec Codec = protoBufCodec{}
+	CSVCodec      Codec = csvCodec{}
+)
+
+var (
+	codecs     = map[string]Codec{}
+	codecsLock sync.RWMutex
+)
+
+func init() {
+	RegisterCodec(JSONCodec, MIMEJSON)
+	RegisterCodec(XMLCodec, MIMEXML, MIMEXML2)
+	RegisterCodec(YAMLCodec, MIMEYAML, MIMEYAML2, "text/yaml")
+	RegisterCodec(MsgPackCodec, MIMEMsgPack, MIMEMsgPack2)
+	RegisterCodec(ProtoBufCodec, MIMEProtoBuf, "application/protobuf")
+	RegisterCodec(CSVCodec, MIMECSV)
+}
+
+// RegisterCodec make c the binder of the content types for Bind, and the renderer of them for Render
+func RegisterCodec(c Codec, contentTypes ...string) {
+	codecsLock.Lock()
+	for _, contentType := range contentTypes {
+		codecs[strings.ToLower(contentType)] = c
+	}
+	codecsLock.Unlock()
+
+	for _, contentType := range contentTypes {
+		RegisterBinder(contentType, codecBinder{codec: c})
+	}
+}
+
+// LookupCodec find the codec registered for the content type, parameters such as charset are ignored
+func LookupCodec(contentType string) (Codec, bool) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, false
+	}
+	codecsLock.RLock()
+	defer codecsLock.RUnlock()
+	c, ok := codecs[mediaType]
+	return c, ok
+}
+
+type codecBinder struct {
+	codec Codec
+}
+
+func (b codecBinder) Name() string {
+	return b.codec.Name()
+}
+
+func (b codecBinder) Bind(ctx *Context, obj interface{}) error {
+	return ctx.BindWith(obj, b.codec)
+}
+
+// BindWith decode the whole body with c and validate obj
+func (ctx *Context) BindWith(obj interface{}, c Codec) error {
+	body, err := ctx.readBody()
+	if err != nil {
+		return err
+	}
+
+	if err = c.Unmarshal(body, obj); err != nil {
+		return err
+	}
+
+	return Validate(obj)
+}
+
+func (ctx *Context) BindYaml(obj interface{}) error {
+	return ctx.BindWith(obj, YAMLCodec)
+}
+
+func (ctx *Context) BindMsgPack(obj interface{}) error {
+	return ctx.BindWith(obj, MsgPackCodec)
+}
+
+func (ctx *Context) BindProtoBuf(obj interface{}) error {
+	return ctx.BindWith(obj, ProtoBufCodec)
+}
+
+func (ctx *Context) BindCsv(obj interface{}) error {
+	return ctx.BindWith(obj, CSVCodec)
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) Name() string {
+	return "json"
+}
+
+func (jsonCodec) ContentType() string {
+	return MIMEJSON
+}
+
+func (jsonCodec) Marshal(obj interface{}) ([]byte, error) {
+	return json.Marshal(obj)
+}
+
+func (jsonCodec) Unmarshal(data []byte, obj interface{}) error {
+	return json.Unmarshal(data, obj)
+}
+
+type xmlCodec struct{}
+
+func (xmlCodec) Name() string {
+	return "xml"
+}
+
+func (xmlCodec) ContentType() string {
+	return MIMEXML
+}
+
+func (xmlCodec) Marshal(obj interface{}) ([]byte, error) {
+	return xml.Marshal(obj)
+}
+
+func (xmlCodec) Unmarshal(data []byte, obj interface{}) error {
+	return xml.Unmarshal(data, obj)
+}
+
+type yamlCodec struct{}
+
+func (yamlCodec) Name() string {
+	return "yaml"
+}
+
+func (yamlCodec) ContentType() string {
+	return MIMEYAML
+}
+
+func (yamlCodec) Marshal(obj interface{}) ([]byte, error) {
+	return yaml.Marshal(obj)
+}
+
+func (yamlCodec) Unmarshal(data []byte, obj interface{}) error {
+	return yaml.Unmarshal(data, obj)
+}
+
+var ErrNotProtoMessage = errors.New("object does not implement proto.Message")
+
+type protoBufCodec struct{}
+
+func (protoBufCodec) Name() string {
+	return "protobuf"
+}
+
+func (protoBufCodec) ContentType() string {
+	return MIMEProtoBuf
+}
+
+func (protoBufCodec) Marshal(obj interface{}) ([]byte, error) {
+	m, ok := obj.(proto.Message)
+	if !ok {
+		return nil, ErrNotProtoMessage
+	}
+	return proto.Marshal(m)
+}
+
+func (protoBufCodec) Unmarshal(data []byte, obj interface{}) error {
+	m, ok := obj.(proto.Message)
+	if !ok {
+		return ErrNotProtoMessage
+	}
+	return proto.Unmarshal(data, m)
+}
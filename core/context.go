@@ -2,10 +2,10 @@ package core
 
 import (
 	"context"
-	"encoding/json"
-	"errors"
-	"io/ioutil"
+	"io"
+	"net"
 	"net/http"
+	"net/url"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -13,7 +13,7 @@ import (
 
 type Context struct {
 	request  *http.Request
-	response http.ResponseWriter
+	response *responseWriter
 	handlers []ControllerHandler
 	index    int
 
@@ -22,14 +22,39 @@ type Context struct {
 	writeMutex *sync.Mutex
 
 	params map[string]string
+
+	// 请求生命周期内缓存解析后的 query 和 form
+	queryCache url.Values
+	formParsed bool
+	formErr    error
+
+	// 请求体大小限制
+	rawBody            io.ReadCloser
+	maxBodySize        int64
+	maxMultipartMemory int64
+
+	trustedProxies []*net.IPNet
+	templates      *HTMLTemplates
+
+	// 渲染等过程中记录的错误
+	errs     []error
+	errMutex sync.Mutex
+
+	sseStarted bool
+
+	// handler 使用的 context，可由中间件替换
+	baseCtx   context.Context
+	baseMutex sync.RWMutex
 }
 
 func NewContext(request *http.Request, response http.ResponseWriter) *Context {
 	return &Context{
 		request:    request,
-		response:   response,
+		response:   newResponseWriter(response),
 		writeMutex: &sync.Mutex{},
 		index:      -1,
+
+		maxMultipartMemory: defaultMultipartMemory,
 	}
 }
 
@@ -45,6 +70,31 @@ func (ctx *Context) GetResponse() http.ResponseWriter {
 	return ctx.response
 }
 
+// SetRequest replace the request handled by the rest of the handler chain
+func (ctx *Context) SetRequest(request *http.Request) {
+	ctx.request = request
+}
+
+// SetResponse replace the writer used by the rest of the handler chain, status and headers
+// written to it stay buffered until the first body write
+func (ctx *Context) SetResponse(response http.ResponseWriter) {
+	if w, ok := response.(*responseWriter); ok {
+		ctx.response = w
+		return
+	}
+	ctx.response = newResponseWriter(response)
+}
+
+// Committed report whether status and headers have been sent to the client
+func (ctx *Context) Committed() bool {
+	return ctx.response.Committed()
+}
+
+// Flush send the buffered status and headers, and flush any buffered body data
+func (ctx *Context) Flush() {
+	ctx.response.Flush()
+}
+
 func (ctx *Context) SetHasStopped() {
 	atomic.AddInt32(&ctx.hasStopped, 1)
 }
@@ -54,7 +104,23 @@ func (ctx *Context) HasStopped() bool {
 }
 
 func (ctx *Context) BaseContext() context.Context {
-	return ctx.request.Context()
+	ctx.baseMutex.RLock()
+	defer ctx.baseMutex.RUnlock()
+	if ctx.baseCtx != nil {
+		return ctx.baseCtx
+	}
+	if ctx.request != nil {
+		return ctx.request.Context()
+	}
+	return context.Background()
+}
+
+// WithContext replace the context seen by Deadline, Done, Err and Value, middlewares use it to
+// add timeouts or values for the rest of the handler chain
+func (ctx *Context) WithContext(c context.Context) {
+	ctx.baseMutex.Lock()
+	defer ctx.baseMutex.Unlock()
+	ctx.baseCtx = c
 }
 
 func (ctx *Context) Deadline() (deadline time.Time, ok bool) {
@@ -73,29 +139,9 @@ func (ctx *Context) Value(key interface{}) interface{} {
 	return ctx.BaseContext().Value(key)
 }
 
+// BindJSON is kept for compatibility, use BindJson or Bind instead
 func (ctx *Context) BindJSON(obj interface{}) error {
-	if ctx.request != nil {
-		body, err := ioutil.ReadAll(ctx.request.Body)
-		if err != nil {
-			return err
-		}
-		//ctx.request.Body = ioutil.NopCloser(bytes.NewBuffer(body))
-		err = json.Unmarshal(body, obj)
-		if err != nil {
-			return err
-		}
-	} else {
-		return errors.New("ctx request empty")
-	}
-	return nil
-}
-
-func (ctx *Context) ExecTimeout() {
-	ctx.WriteMutex().Lock()
-	defer ctx.writeMutex.Unlock()
-	ctx.SetHasStopped()
-	ctx.response.WriteHeader(http.StatusInternalServerError)
-	ctx.response.Write([]byte("time out"))
+	return ctx.BindJson(obj)
 }
 
 func (ctx *Context) ExecPanic() {
@@ -1,7 +1,9 @@
 package core
 
 import (
+	"html/template"
 	"log"
+	"net"
 	"net/http"
 	"strings"
 )
@@ -9,6 +11,18 @@ import (
 type Core struct {
 	router      map[string]*Tree
 	middlewares []ControllerHandler
+
+	maxBodySize        int64
+	maxMultipartMemory int64
+
+	trustedProxies []*net.IPNet
+	proxyProtocol  []string
+
+	funcMap    template.FuncMap
+	templates  *HTMLTemplates
+	htmlReload bool
+
+	errorHandler ErrorHandler
 }
 
 func New() *Core {
@@ -17,9 +31,12 @@ func New() *Core {
 	router["POST"] = NewTree()
 	router["PUT"] = NewTree()
 	router["DELETE"] = NewTree()
+	router["HEAD"] = NewTree()
 
 	return &Core{
-		router: router,
+		router:             router,
+		maxMultipartMemory: defaultMultipartMemory,
+		errorHandler:       defaultErrorHandler,
 	}
 }
 
@@ -55,6 +72,13 @@ func (c *Core) Delete(uri string, handlers ...ControllerHandler) {
 	}
 }
 
+func (c *Core) Head(uri string, handlers ...ControllerHandler) {
+	allHandlers := append(c.middlewares, handlers...)
+	if err := c.router["HEAD"].AddRouter(uri, allHandlers); err != nil {
+		log.Fatal(err)
+	}
+}
+
 func (c *Core) FindRouteByRequest(request *http.Request) []ControllerHandler {
 	uri := request.URL.Path
 	method := request.Method
@@ -79,6 +103,12 @@ func (c *Core) FindRouteNodeByRequest(request *http.Request) *node {
 
 func (c *Core) ServeHTTP(response http.ResponseWriter, request *http.Request) {
 	ctx := NewContext(request, response)
+	ctx.SetMaxMultipartMemory(c.maxMultipartMemory)
+	ctx.trustedProxies = c.trustedProxies
+	ctx.templates = c.templates
+	if c.maxBodySize > 0 {
+		ctx.SetMaxBodySize(c.maxBodySize)
+	}
 
 	node := c.FindRouteNodeByRequest(request)
 	if node == nil {
@@ -91,9 +121,26 @@ func (c *Core) ServeHTTP(response http.ResponseWriter, request *http.Request) {
 	params := node.parseParamsFromEndNode(request.URL.Path)
 	ctx.SetParams(params)
 
-	if err := ctx.Next(); err != nil {
-		ctx.SetStatus(http.StatusInternalServerError).JSON("INNER ERROR")
-		return
+	err := ctx.Next()
+	if err == nil {
+		if errs := ctx.Errors(); len(errs) > 0 {
+			err = errs[0]
+		}
+	}
+	if err != nil && !ctx.HasStopped() {
+		// 响应已经开始发送，再写错误只会破坏响应体
+		if ctx.Committed() {
+			log.Printf("error after response committed: %v", err)
+		} else {
+			c.errorHandler(ctx, err)
+		}
+	}
+
+	ctx.WriteMutex().Lock()
+	defer ctx.WriteMutex().Unlock()
+	// handler stopped by timeout may still own the current writer
+	if !ctx.HasStopped() {
+		ctx.response.WriteHeaderNow()
 	}
 }
 
@@ -0,0 +1,193 @@
+package core
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const tagCSV = "csv"
+
+var errCSVType = errors.New("csv codec supports [][]string and slices of structs")
+
+// csvCodec map rows to [][]string, or to slices of structs by the header row and `csv` tags
+type csvCodec struct{}
+
+func (csvCodec) Name() string {
+	return "csv"
+}
+
+func (csvCodec) ContentType() string {
+	return MIMECSV + "; charset=utf-8"
+}
+
+func (csvCodec) Marshal(obj interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+
+	if records, ok := obj.([][]string); ok {
+		if err := w.WriteAll(records); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+
+	rv := reflect.Indirect(reflect.ValueOf(obj))
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return nil, errCSVType
+	}
+	elemType := rv.Type().Elem()
+	if elemType.Kind() == reflect.Ptr {
+		elemType = elemType.Elem()
+	}
+	if elemType.Kind() != reflect.Struct {
+		return nil, errCSVType
+	}
+
+	fields, header := csvFields(elemType)
+	if err := w.Write(header); err != nil {
+		return nil, err
+	}
+	for i := 0; i < rv.Len(); i++ {
+		elem := reflect.Indirect(rv.Index(i))
+		row := make([]string, len(fields))
+		if elem.IsValid() {
+			for j, idx := range fields {
+				row[j] = formatCSVValue(elem.Field(idx))
+			}
+		}
+		if err := w.Write(row); err != nil {
+			return nil, err
+		}
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
+
+func (csvCodec) Unmarshal(data []byte, obj interface{}) error {
+	records, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+	if err != nil {
+		return err
+	}
+
+	if p, ok := obj.(*[][]string); ok {
+		*p = records
+		return nil
+	}
+
+	rv := reflect.ValueOf(obj)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Slice {
+		return errCSVType
+	}
+	slice := rv.Elem()
+	elemType := slice.Type().Elem()
+	isPtr := elemType.Kind() == reflect.Ptr
+	if isPtr {
+		elemType = elemType.Elem()
+	}
+	if elemType.Kind() != reflect.Struct {
+		return errCSVType
+	}
+	if len(records) == 0 {
+		return nil
+	}
+
+	header := records[0]
+	out := reflect.MakeSlice(slice.Type(), 0, len(records)-1)
+	for line, record := range records[1:] {
+		values := make(map[string][]string, len(header))
+		for i, name := range header {
+			if i < len(record) {
+				values[strings.TrimSpace(name)] = []string{record[i]}
+			}
+		}
+		src := mapSource(tagCSV, values)
+		src.byName = true
+
+		elem := reflect.New(elemType).Elem()
+		if err := mapStruct(elem, []tagSource{src}, ""); err != nil {
+			return fmt.Errorf("csv line %d: %w", line+2, err)
+		}
+		if isPtr {
+			elem = elem.Addr()
+		}
+		out = reflect.Append(out, elem)
+	}
+	slice.Set(out)
+	return nil
+}
+
+// csvFields return the indexes and the column names of the exported scalar fields of t
+func csvFields(t reflect.Type) ([]int, []string) {
+	var idx []int
+	var names []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" || !isCSVScalar(field.Type) {
+			continue
+		}
+		name := strings.Split(field.Tag.Get(tagCSV), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		idx = append(idx, i)
+		names = append(names, name)
+	}
+	return idx, names
+}
+
+func isCSVScalar(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if isScalarType(t) {
+		return true
+	}
+	switch t.Kind() {
+	case reflect.Map, reflect.Struct, reflect.Slice, reflect.Array, reflect.Func, reflect.Chan, reflect.Interface:
+		return false
+	}
+	return true
+}
+
+func formatCSVValue(v reflect.Value) string {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+	if t, ok := v.Interface().(time.Time); ok {
+		return t.Format(time.RFC3339)
+	}
+	if m, ok := v.Interface().(encoding.TextMarshaler); ok {
+		if b, err := m.MarshalText(); err == nil {
+			return string(b)
+		}
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return v.String()
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if v.Type() == durationType {
+			return time.Duration(v.Int()).String()
+		}
+		return strconv.FormatInt(v.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, v.Type().Bits())
+	}
+	return fmt.Sprint(v.Interface())
+}
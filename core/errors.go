@@ -0,0 +1,42 @@
+package core
+
+import (
+	"net/http"
+)
+
+// ErrorHandler respond to the error returned by the handler chain or recorded with ctx.Error
+type ErrorHandler func(ctx *Context, err error)
+
+// SetErrorHandler replace the default handler, which responds 413 for body size errors and
+// 500 "INNER ERROR" for the rest; it is not called once the status and headers have been sent,
+// such errors are only logged
+func (c *Core) SetErrorHandler(h ErrorHandler) {
+	c.errorHandler = h
+}
+
+func defaultErrorHandler(ctx *Context, err error) {
+	if errorStatus(err) == http.StatusRequestEntityTooLarge {
+		ctx.SetStatus(http.StatusRequestEntityTooLarge).JSON(err.Error())
+		return
+	}
+	debugPrint("handler error: %v", err)
+	ctx.SetStatus(http.StatusInternalServerError).JSON("INNER ERROR")
+}
+
+// Error record an error that happened while rendering, it is passed to the error handler when
+// the handler chain returns without error
+func (ctx *Context) Error(err error) {
+	if err == nil {
+		return
+	}
+	ctx.errMutex.Lock()
+	defer ctx.errMutex.Unlock()
+	ctx.errs = append(ctx.errs, err)
+}
+
+// Errors return the errors recorded with Error
+func (ctx *Context) Errors() []error {
+	ctx.errMutex.Lock()
+	defer ctx.errMutex.Unlock()
+	return append([]error(nil), ctx.errs...)
+}
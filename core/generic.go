@@ -0,0 +1,48 @@
+package core
+
+import (
+	"errors"
+	"reflect"
+)
+
+var ErrInvalidUUID = errors.New("invalid uuid")
+
+// UUID is a string in the 8-4-4-4-12 hex form, it can be used with Query, Param and Form
+type UUID string
+
+func (u *UUID) UnmarshalText(text []byte) error {
+	if !uuidRegexp.Match(text) {
+		return ErrInvalidUUID
+	}
+	*u = UUID(text)
+	return nil
+}
+
+// Query get the query value of key as T, such as core.Query[int](ctx, "page"), T may be any
+// number, bool, string, time.Time (RFC3339), time.Duration or encoding.TextUnmarshaler
+func Query[T any](ctx *Context, key string) (T, error) {
+	return parseValue[T]("query", key, ctx.queryValue)
+}
+
+// Param get the router param of key as T, see Query for the supported types
+func Param[T any](ctx *Context, key string) (T, error) {
+	return parseValue[T]("param", key, ctx.paramValue)
+}
+
+// Form get the post form value of key as T, see Query for the supported types
+func Form[T any](ctx *Context, key string) (T, error) {
+	return parseValue[T]("form", key, ctx.formValue)
+}
+
+func parseValue[T any](source, key string, lookup func(key string) (string, error)) (T, error) {
+	var ret T
+	s, err := lookup(key)
+	if err != nil {
+		return ret, err
+	}
+	if err := setValue(reflect.ValueOf(&ret).Elem(), s, reflect.StructField{}); err != nil {
+		var zero T
+		return zero, valueError(source, key, s, err)
+	}
+	return ret, nil
+}
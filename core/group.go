@@ -7,6 +7,7 @@ type IGroup interface {
 	Post(string, ...ControllerHandler)
 	Put(string, ...ControllerHandler)
 	Delete(string, ...ControllerHandler)
+	Head(string, ...ControllerHandler)
 	Group(string) IGroup
 	Use(middlewares ...ControllerHandler)
 }
@@ -42,6 +43,12 @@ func (g *Group) Delete(uri string, handlers ...ControllerHandler) {
 	g.core.Delete(uri, allHandlers...)
 }
 
+func (g *Group) Head(uri string, handlers ...ControllerHandler) {
+	uri = g.getAbsolutePrefix() + uri
+	allHandlers := append(g.getMiddlewares(), handlers...)
+	g.core.Head(uri, allHandlers...)
+}
+
 func (g *Group) Group(prefix string) IGroup {
 	group := NewGroup(g.core, prefix)
 	group.parent = g
@@ -0,0 +1,358 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+var (
+	ErrSlowConsumer = errors.New("websocket: slow consumer")
+	ErrClientClosed = errors.New("websocket: client closed")
+)
+
+// 断开客户端时发送关闭帧的最长等待时间
+const hubCloseGracePeriod = time.Second
+
+// HubConfig configure the clients accepted by Hub.Handler
+type HubConfig struct {
+	Upgrade UpgradeConfig
+	// 每个客户端的发送队列长度，队列满时断开该客户端，默认 64
+	SendBuffer int
+	// ping 间隔，超过两个间隔没有收到 pong 即断开，默认 30s
+	PingInterval time.Duration
+	// 单条消息的写超时，默认 10s
+	WriteTimeout time.Duration
+	// ClientID name the client in presence lists, by default the remote address; auth
+	// middlewares can expose the user through ctx.Value for it
+	ClientID func(ctx *Context) string
+
+	OnMessage func(client *HubClient, messageType int, data []byte)
+	OnJoin    func(client *HubClient, room string)
+	OnLeave   func(client *HubClient, room string)
+}
+
+// Hub fan out websocket messages to the clients of named rooms
+type Hub struct {
+	config HubConfig
+
+	mutex sync.RWMutex
+	rooms map[string]map[*HubClient]struct{}
+}
+
+func NewHub(config HubConfig) *Hub {
+	if config.SendBuffer <= 0 {
+		config.SendBuffer = 64
+	}
+	if config.PingInterval <= 0 {
+		config.PingInterval = 30 * time.Second
+	}
+	if config.WriteTimeout <= 0 {
+		config.WriteTimeout = 10 * time.Second
+	}
+	if config.ClientID == nil {
+		config.ClientID = func(ctx *Context) string {
+			return ctx.GetRequest().RemoteAddr
+		}
+	}
+	return &Hub{
+		config: config,
+		rooms:  map[string]map[*HubClient]struct{}{},
+	}
+}
+
+// Handler upgrade the request and serve the client until it disconnects, the client joins the
+// rooms given here and the one named by the :room path param, so a route group such as
+// Group("/ws") with auth middlewares can expose Get("/:room", hub.Handler())
+func (h *Hub) Handler(rooms ...string) ControllerHandler {
+	return func(ctx *Context) error {
+		conn, err := ctx.UpgradeWithConfig(h.config.Upgrade)
+		if err != nil {
+			return err
+		}
+
+		client := &HubClient{
+			hub:   h,
+			conn:  conn,
+			id:    h.config.ClientID(ctx),
+			send:  make(chan hubMessage, h.config.SendBuffer),
+			done:  make(chan struct{}),
+			rooms: map[string]struct{}{},
+		}
+		go client.writeLoop()
+		go func() {
+			select {
+			case <-ctx.Done():
+				client.Close()
+			case <-client.done:
+			}
+		}()
+
+		for _, room := range rooms {
+			h.Join(room, client)
+		}
+		if room, ok := ctx.ParamString("room", ""); ok && room != "" {
+			h.Join(room, client)
+		}
+
+		client.readLoop()
+		// 先关闭再移出房间，之后的 Join 都会被拒绝
+		client.Close()
+		h.remove(client)
+		return nil
+	}
+}
+
+// Join add client to room, a client that is already closed is rejected with ErrClientClosed
+func (h *Hub) Join(room string, client *HubClient) error {
+	h.mutex.Lock()
+	select {
+	case <-client.done:
+		h.mutex.Unlock()
+		return ErrClientClosed
+	default:
+	}
+	members, ok := h.rooms[room]
+	if !ok {
+		members = map[*HubClient]struct{}{}
+		h.rooms[room] = members
+	}
+	_, joined := members[client]
+	members[client] = struct{}{}
+	client.rooms[room] = struct{}{}
+	h.mutex.Unlock()
+
+	if !joined && h.config.OnJoin != nil {
+		h.config.OnJoin(client, room)
+	}
+	return nil
+}
+
+// Leave remove client from room, empty rooms are dropped
+func (h *Hub) Leave(room string, client *HubClient) {
+	h.mutex.Lock()
+	left := h.leave(room, client)
+	h.mutex.Unlock()
+
+	if left && h.config.OnLeave != nil {
+		h.config.OnLeave(client, room)
+	}
+}
+
+func (h *Hub) leave(room string, client *HubClient) bool {
+	members, ok := h.rooms[room]
+	if !ok {
+		return false
+	}
+	if _, ok := members[client]; !ok {
+		return false
+	}
+	delete(members, client)
+	delete(client.rooms, room)
+	if len(members) == 0 {
+		delete(h.rooms, room)
+	}
+	return true
+}
+
+func (h *Hub) remove(client *HubClient) {
+	h.mutex.Lock()
+	var left []string
+	for room := range client.rooms {
+		if h.leave(room, client) {
+			left = append(left, room)
+		}
+	}
+	h.mutex.Unlock()
+
+	if h.config.OnLeave != nil {
+		for _, room := range left {
+			h.config.OnLeave(client, room)
+		}
+	}
+}
+
+// Broadcast queue a message for every client of room without blocking, clients whose queue is
+// full are disconnected
+func (h *Hub) Broadcast(room string, messageType int, data []byte) {
+	h.mutex.RLock()
+	clients := make([]*HubClient, 0, len(h.rooms[room]))
+	for client := range h.rooms[room] {
+		clients = append(clients, client)
+	}
+	h.mutex.RUnlock()
+
+	for _, client := range clients {
+		client.Send(messageType, data)
+	}
+}
+
+// BroadcastJSON marshal obj and broadcast it as a text message
+func (h *Hub) BroadcastJSON(room string, obj interface{}) error {
+	b, err := json.Marshal(obj)
+	if err != nil {
+		return err
+	}
+	h.Broadcast(room, TextMessage, b)
+	return nil
+}
+
+// Presence return the sorted client ids in room
+func (h *Hub) Presence(room string) []string {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+
+	ids := make([]string, 0, len(h.rooms[room]))
+	for client := range h.rooms[room] {
+		ids = append(ids, client.id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Rooms return the sorted names of the rooms with at least one client
+func (h *Hub) Rooms() []string {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+
+	rooms := make([]string, 0, len(h.rooms))
+	for room := range h.rooms {
+		rooms = append(rooms, room)
+	}
+	sort.Strings(rooms)
+	return rooms
+}
+
+type hubMessage struct {
+	messageType int
+	data        []byte
+}
+
+// HubClient is a connection served by Hub.Handler
+type HubClient struct {
+	hub  *Hub
+	conn *WebSocketConn
+	id   string
+
+	send      chan hubMessage
+	done      chan struct{}
+	closeOnce sync.Once
+	// 由 writeLoop 在退出时发送的关闭帧
+	closeCode   int
+	closeReason string
+
+	// 由 hub.mutex 保护
+	rooms map[string]struct{}
+}
+
+func (c *HubClient) ID() string {
+	return c.id
+}
+
+func (c *HubClient) Conn() *WebSocketConn {
+	return c.conn
+}
+
+// Rooms return the sorted names of the rooms the client is in
+func (c *HubClient) Rooms() []string {
+	c.hub.mutex.RLock()
+	defer c.hub.mutex.RUnlock()
+
+	rooms := make([]string, 0, len(c.rooms))
+	for room := range c.rooms {
+		rooms = append(rooms, room)
+	}
+	sort.Strings(rooms)
+	return rooms
+}
+
+// Send queue a message for the client, when the queue is full the client is disconnected and
+// ErrSlowConsumer returned
+func (c *HubClient) Send(messageType int, data []byte) error {
+	select {
+	case <-c.done:
+		return ErrClientClosed
+	default:
+	}
+
+	select {
+	case c.send <- hubMessage{messageType: messageType, data: data}:
+		return nil
+	default:
+		c.close(ClosePolicyViolation, "slow consumer")
+		return ErrSlowConsumer
+	}
+}
+
+// SendJSON marshal obj and queue it as a text message
+func (c *HubClient) SendJSON(obj interface{}) error {
+	b, err := json.Marshal(obj)
+	if err != nil {
+		return err
+	}
+	return c.Send(TextMessage, b)
+}
+
+// Close disconnect the client, Hub.Handler then removes it from its rooms
+func (c *HubClient) Close() {
+	c.close(CloseNormalClosure, "")
+}
+
+// close never takes the connection write lock, a write blocked on a slow client is cut short
+// by the deadline and writeLoop sends the close frame on its way out
+func (c *HubClient) close(code int, reason string) {
+	c.closeOnce.Do(func() {
+		c.closeCode, c.closeReason = code, reason
+		close(c.done)
+		c.conn.SetWriteDeadline(time.Now())
+	})
+}
+
+func (c *HubClient) readLoop() {
+	pongWait := 2 * c.hub.config.PingInterval
+	c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+
+	for {
+		messageType, data, err := c.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		if c.hub.config.OnMessage != nil {
+			c.hub.config.OnMessage(c, messageType, data)
+		}
+	}
+}
+
+func (c *HubClient) writeLoop() {
+	ticker := time.NewTicker(c.hub.config.PingInterval)
+	defer ticker.Stop()
+	defer func() {
+		<-c.done
+		c.conn.SetWriteDeadline(time.Now().Add(hubCloseGracePeriod))
+		c.conn.CloseWithCode(c.closeCode, c.closeReason)
+	}()
+
+	for {
+		select {
+		case <-c.done:
+			return
+		case msg := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(c.hub.config.WriteTimeout))
+			if err := c.conn.WriteMessage(msg.messageType, msg.data); err != nil {
+				c.Close()
+				return
+			}
+		case <-ticker.C:
+			c.conn.SetWriteDeadline(time.Now().Add(c.hub.config.WriteTimeout))
+			if err := c.conn.Ping(nil); err != nil {
+				c.Close()
+				return
+			}
+		}
+	}
+}
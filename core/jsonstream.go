@@ -0,0 +1,125 @@
+package core
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// JSONStream decode a JSON array or newline delimited JSON body one record at a time:
+//
+//	stream := ctx.JSONStream()
+//	for stream.Next(&record) {
+//		...
+//	}
+//	if err := stream.Err(); err != nil {
+//		...
+//	}
+type JSONStream struct {
+	ctx     *Context
+	reader  *bufio.Reader
+	dec     *json.Decoder
+	array   bool
+	started bool
+	done    bool
+	index   int
+	err     error
+}
+
+// JSONStream read the body lazily, so the body size limit and the cancellation of the handler
+// context apply while records are consumed
+func (ctx *Context) JSONStream() *JSONStream {
+	s := &JSONStream{ctx: ctx}
+	if ctx.request == nil || ctx.request.Body == nil {
+		s.err = errors.New("ctx.request empty")
+		return s
+	}
+	s.reader = bufio.NewReader(&contextReader{ctx: ctx, r: ctx.request.Body})
+	s.dec = json.NewDecoder(s.reader)
+	return s
+}
+
+// Next decode the next record into obj and validate it, it returns false at the end of the body or on error
+func (s *JSONStream) Next(obj interface{}) bool {
+	if s.err != nil || s.done {
+		return false
+	}
+	if !s.started {
+		s.started = true
+		if s.err = s.start(); s.err != nil || s.done {
+			return false
+		}
+	}
+
+	if s.array && !s.dec.More() {
+		s.done = true
+		if _, err := s.dec.Token(); err != nil {
+			s.err = s.ctx.bodyError(err)
+		}
+		return false
+	}
+
+	if err := s.dec.Decode(obj); err != nil {
+		if err == io.EOF && !s.array {
+			s.done = true
+			return false
+		}
+		s.err = fmt.Errorf("record %d: %w", s.index, s.ctx.bodyError(err))
+		return false
+	}
+	if err := Validate(obj); err != nil {
+		s.err = fmt.Errorf("record %d: %w", s.index, err)
+		return false
+	}
+	s.index++
+	return true
+}
+
+// start check whether the body is a JSON array, and consume its opening bracket
+func (s *JSONStream) start() error {
+	for {
+		b, err := s.reader.Peek(1)
+		if err == io.EOF {
+			s.done = true
+			return nil
+		}
+		if err != nil {
+			return s.ctx.bodyError(err)
+		}
+		switch b[0] {
+		case ' ', '\t', '\r', '\n':
+			s.reader.ReadByte()
+			continue
+		case '[':
+			s.array = true
+			if _, err := s.dec.Token(); err != nil {
+				return s.ctx.bodyError(err)
+			}
+		}
+		return nil
+	}
+}
+
+// Count return the number of records decoded so far
+func (s *JSONStream) Count() int {
+	return s.index
+}
+
+func (s *JSONStream) Err() error {
+	return s.err
+}
+
+// contextReader stop reading once the handler context is done
+type contextReader struct {
+	ctx *Context
+	r   io.Reader
+}
+
+func (r *contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.r.Read(p)
+}
@@ -0,0 +1,120 @@
+package core
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+)
+
+var ErrBodyTooLarge = errors.New("request body too large")
+
+// SetMaxBodySize limit the request body of every route to n bytes, 0 means no limit
+func (c *Core) SetMaxBodySize(n int64) {
+	c.maxBodySize = n
+}
+
+// SetMaxMultipartMemory set how many bytes of a multipart form are kept in memory, the rest go to temp files
+func (c *Core) SetMaxMultipartMemory(n int64) {
+	c.maxMultipartMemory = n
+}
+
+// SetMaxBodySize limit the request body to n bytes with http.MaxBytesReader, it replaces any
+// earlier limit so a route can raise or lower the global one, 0 means no limit
+func (ctx *Context) SetMaxBodySize(n int64) {
+	if ctx.request == nil || ctx.request.Body == nil {
+		return
+	}
+	if ctx.rawBody == nil {
+		ctx.rawBody = ctx.request.Body
+	}
+	ctx.maxBodySize = n
+	if n <= 0 {
+		ctx.request.Body = ctx.rawBody
+		return
+	}
+	ctx.request.Body = http.MaxBytesReader(ctx.response, ctx.rawBody, n)
+}
+
+// SetBody replace the request body, middlewares such as Decompress use it so that a later
+// SetMaxBodySize limits the new body instead of restoring the original one
+func (ctx *Context) SetBody(body io.ReadCloser) {
+	if ctx.request == nil {
+		return
+	}
+	ctx.rawBody = body
+	ctx.request.Body = body
+}
+
+func (ctx *Context) SetMaxMultipartMemory(n int64) {
+	ctx.maxMultipartMemory = n
+}
+
+// LimitReader is io.LimitReader failing with Err instead of a silent EOF once R has more than N bytes
+type LimitReader struct {
+	R   io.Reader
+	N   int64
+	Err error
+}
+
+func NewLimitReader(r io.Reader, n int64, err error) *LimitReader {
+	return &LimitReader{R: r, N: n, Err: err}
+}
+
+func (l *LimitReader) Read(p []byte) (int, error) {
+	if l.N <= 0 {
+		// exactly N bytes is fine, anything more is not, and a read error is not a clean end
+		var one [1]byte
+		n, err := l.R.Read(one[:])
+		if n > 0 {
+			return 0, l.Err
+		}
+		if err != nil && err != io.EOF {
+			return 0, err
+		}
+		return 0, io.EOF
+	}
+	if int64(len(p)) > l.N {
+		p = p[:l.N]
+	}
+	n, err := l.R.Read(p)
+	l.N -= int64(n)
+	return n, err
+}
+
+// readBody read the whole body within the size limit and restore it for later readers
+func (ctx *Context) readBody() ([]byte, error) {
+	if ctx.request == nil {
+		return nil, errors.New("ctx.request empty")
+	}
+	if ctx.request.Body == nil {
+		return nil, nil
+	}
+
+	body, err := ioutil.ReadAll(ctx.request.Body)
+	if err != nil {
+		return nil, ctx.bodyError(err)
+	}
+
+	ctx.SetBody(ioutil.NopCloser(bytes.NewBuffer(body)))
+	return body, nil
+}
+
+// bodyError turn the error of http.MaxBytesReader into ErrBodyTooLarge
+func (ctx *Context) bodyError(err error) error {
+	var maxErr *http.MaxBytesError
+	if errors.As(err, &maxErr) {
+		return fmt.Errorf("%w: limit is %d bytes", ErrBodyTooLarge, maxErr.Limit)
+	}
+	return err
+}
+
+// errorStatus choose the status code for errors returned by binding
+func errorStatus(err error) int {
+	if errors.Is(err, ErrBodyTooLarge) || errors.Is(err, ErrFileTooLarge) {
+		return http.StatusRequestEntityTooLarge
+	}
+	return http.StatusBadRequest
+}
@@ -0,0 +1,43 @@
+package core
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestLimitReader(t *testing.T) {
+	errLimit := errors.New("limit")
+	errBroken := errors.New("broken")
+
+	cases := []struct {
+		name string
+		r    io.Reader
+		n    int64
+		data string
+		err  error
+	}{
+		{name: "below the limit", r: strings.NewReader("abc"), n: 5, data: "abc"},
+		{name: "exactly the limit", r: strings.NewReader("abcde"), n: 5, data: "abcde"},
+		{name: "over the limit", r: strings.NewReader("abcdef"), n: 5, data: "abcde", err: errLimit},
+		{name: "error before the limit", r: io.MultiReader(strings.NewReader("abc"), iotest.ErrReader(errBroken)), n: 5, data: "abc", err: errBroken},
+		// 恰好读满时底层出错，不能当作正常结束
+		{name: "error at the limit", r: io.MultiReader(strings.NewReader("abcde"), iotest.ErrReader(errBroken)), n: 5, data: "abcde", err: errBroken},
+		{name: "one byte reads", r: iotest.OneByteReader(strings.NewReader("abcdef")), n: 5, data: "abcde", err: errLimit},
+	}
+
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			data, err := io.ReadAll(NewLimitReader(c.r, c.n, errLimit))
+			if string(data) != c.data {
+				t.Fatalf("got %q, want %q", data, c.data)
+			}
+			if !errors.Is(err, c.err) {
+				t.Fatalf("got error %v, want %v", err, c.err)
+			}
+		})
+	}
+}
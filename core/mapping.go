@@ -0,0 +1,310 @@
+package core
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"net/textproto"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	tagQuery   = "query"
+	tagPath    = "path"
+	tagHeader  = "header"
+	tagForm    = "form"
+	tagDefault = "default"
+	tagTime    = "time_format"
+)
+
+var (
+	timeType            = reflect.TypeOf(time.Time{})
+	durationType        = reflect.TypeOf(time.Duration(0))
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// tagSource give the values of one request part for the fields carrying tag
+type tagSource struct {
+	tag    string
+	lookup func(key string) ([]string, bool)
+	// byName use the field name when the field has no tag
+	byName bool
+}
+
+func mapSource(tag string, values map[string][]string) tagSource {
+	return tagSource{
+		tag: tag,
+		lookup: func(key string) ([]string, bool) {
+			vs, ok := values[key]
+			return vs, ok && len(vs) > 0
+		},
+	}
+}
+
+func (ctx *Context) querySource() tagSource {
+	return mapSource(tagQuery, ctx.query())
+}
+
+func (ctx *Context) pathSource() tagSource {
+	return tagSource{
+		tag: tagPath,
+		lookup: func(key string) ([]string, bool) {
+			v, ok := ctx.params[key]
+			return []string{v}, ok
+		},
+	}
+}
+
+func (ctx *Context) headerSource() tagSource {
+	return tagSource{
+		tag: tagHeader,
+		lookup: func(key string) ([]string, bool) {
+			if ctx.request == nil {
+				return nil, false
+			}
+			vs, ok := ctx.request.Header[textproto.CanonicalMIMEHeaderKey(key)]
+			return vs, ok && len(vs) > 0
+		},
+	}
+}
+
+func (ctx *Context) formSource() tagSource {
+	values, _ := ctx.postForm()
+	return mapSource(tagForm, values)
+}
+
+// BindQuery set the fields tagged with `query:"name"` from the query string; like the other
+// partial binders it does not validate, so it can be followed by BindJson or others, call
+// ctx.Validate once obj is complete
+func (ctx *Context) BindQuery(obj interface{}) error {
+	return mapping(obj, ctx.querySource())
+}
+
+// BindPath set the fields tagged with `path:"id"` from the router params
+func (ctx *Context) BindPath(obj interface{}) error {
+	return mapping(obj, ctx.pathSource())
+}
+
+// BindHeader set the fields tagged with `header:"X-Tenant"` from the request headers
+func (ctx *Context) BindHeader(obj interface{}) error {
+	return mapping(obj, ctx.headerSource())
+}
+
+// BindForm set the fields tagged with `form:"name"` from the post form
+func (ctx *Context) BindForm(obj interface{}) error {
+	if err := ctx.parseForm(); err != nil {
+		return err
+	}
+	return mapping(obj, ctx.formSource())
+}
+
+// BindRequest set the fields of obj from the query, path, header and form tags at once,
+// tagged fields without any value take the `default:"..."` tag, then validate obj
+func (ctx *Context) BindRequest(obj interface{}) error {
+	if err := ctx.parseForm(); err != nil {
+		return err
+	}
+	return mappingValidate(obj, ctx.querySource(), ctx.pathSource(), ctx.headerSource(), ctx.formSource())
+}
+
+func mapping(obj interface{}, sources ...tagSource) error {
+	rv := reflect.ValueOf(obj)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return errors.New("binding object must be a non-nil pointer")
+	}
+	rv = rv.Elem()
+	if rv.Kind() != reflect.Struct {
+		return errors.New("binding object must point to a struct")
+	}
+	return mapStruct(rv, sources, "")
+}
+
+// mappingValidate map obj and validate it, for binders that fill obj completely
+func mappingValidate(obj interface{}, sources ...tagSource) error {
+	if err := mapping(obj, sources...); err != nil {
+		return err
+	}
+	return Validate(obj)
+}
+
+func mapStruct(rv reflect.Value, sources []tagSource, prefix string) error {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		if field.PkgPath != "" && !field.Anonymous {
+			continue
+		}
+		fv := rv.Field(i)
+
+		vs, key, source, tagged, skip := lookupField(field, sources)
+		if skip {
+			continue
+		}
+		// 只有绑定到本次来源的字段才取默认值，否则后面的 BindPath 等会覆盖 BindQuery 已设置的值
+		if vs == nil && tagged {
+			if def, ok := field.Tag.Lookup(tagDefault); ok {
+				vs, key, source = []string{def}, field.Name, tagDefault
+			}
+		}
+		if vs != nil {
+			if !fv.CanSet() {
+				continue
+			}
+			if err := setField(fv, vs, field); err != nil {
+				return valueError(source, prefix+key, vs[0], err)
+			}
+			continue
+		}
+
+		// nested struct without a value of its own
+		ft := field.Type
+		isPtr := ft.Kind() == reflect.Ptr
+		if isPtr {
+			ft = ft.Elem()
+		}
+		if ft.Kind() != reflect.Struct || isScalarType(ft) {
+			continue
+		}
+		target := fv
+		if isPtr {
+			target = reflect.New(ft).Elem()
+		}
+		if !target.CanSet() {
+			continue
+		}
+		if err := mapStruct(target, sources, prefix+field.Name+"."); err != nil {
+			return err
+		}
+		if isPtr && !target.IsZero() {
+			fv.Set(target.Addr())
+		}
+	}
+	return nil
+}
+
+// lookupField return the values of the first source the field is tagged for, tagged reports
+// whether any of sources binds the field at all
+func lookupField(field reflect.StructField, sources []tagSource) (vs []string, key string, source string, tagged bool, skip bool) {
+	for _, src := range sources {
+		name, ok := field.Tag.Lookup(src.tag)
+		if name == "-" {
+			return nil, "", "", false, true
+		}
+		name = strings.Split(name, ",")[0]
+		if !ok || name == "" {
+			if !src.byName {
+				continue
+			}
+			name = field.Name
+		}
+		tagged = true
+		if vs, found := src.lookup(name); found {
+			return vs, name, src.tag, true, false
+		}
+	}
+	return nil, "", "", tagged, false
+}
+
+func isScalarType(t reflect.Type) bool {
+	return t == timeType || reflect.PtrTo(t).Implements(textUnmarshalerType)
+}
+
+func setField(v reflect.Value, vs []string, field reflect.StructField) error {
+	if v.Kind() == reflect.Slice && v.Type().Elem().Kind() != reflect.Uint8 && !isScalarType(v.Type()) {
+		slice := reflect.MakeSlice(v.Type(), len(vs), len(vs))
+		for i, s := range vs {
+			if err := setValue(slice.Index(i), s, field); err != nil {
+				return err
+			}
+		}
+		v.Set(slice)
+		return nil
+	}
+	return setValue(v, vs[0], field)
+}
+
+func setValue(v reflect.Value, s string, field reflect.StructField) error {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return setValue(v.Elem(), s, field)
+	}
+
+	if v.CanAddr() && v.Addr().Type().Implements(textUnmarshalerType) && v.Type() != timeType {
+		return v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s))
+	}
+
+	switch v.Type() {
+	case timeType:
+		return setTime(v, s, field)
+	case durationType:
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(n)
+	default:
+		return fmt.Errorf("unsupported kind %s", v.Kind())
+	}
+	return nil
+}
+
+// setTime parse s with the `time_format` tag, RFC3339 by default, "unix" for unix seconds
+func setTime(v reflect.Value, s string, field reflect.StructField) error {
+	if s == "" {
+		v.Set(reflect.ValueOf(time.Time{}))
+		return nil
+	}
+	layout := field.Tag.Get(tagTime)
+	if layout == "unix" {
+		sec, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(time.Unix(sec, 0)))
+		return nil
+	}
+	if layout == "" {
+		layout = time.RFC3339
+	}
+	t, err := time.Parse(layout, s)
+	if err != nil {
+		return err
+	}
+	v.Set(reflect.ValueOf(t))
+	return nil
+}
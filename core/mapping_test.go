@@ -0,0 +1,41 @@
+package core
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPartialBindersKeepDefaults(t *testing.T) {
+	type request struct {
+		Page   int    `query:"page" default:"1"`
+		ID     int    `path:"id"`
+		Tenant string `header:"X-Tenant" default:"public"`
+	}
+
+	ctx := NewContext(httptest.NewRequest("GET", "/users/7?page=5", nil), httptest.NewRecorder())
+	ctx.SetParams(map[string]string{"id": "7"})
+
+	var req request
+	if err := ctx.BindQuery(&req); err != nil {
+		t.Fatal(err)
+	}
+	if err := ctx.BindPath(&req); err != nil {
+		t.Fatal(err)
+	}
+	if err := ctx.BindHeader(&req); err != nil {
+		t.Fatal(err)
+	}
+	if req.Page != 5 || req.ID != 7 || req.Tenant != "public" {
+		t.Fatalf("got %+v", req)
+	}
+
+	req = request{}
+	ctx = NewContext(httptest.NewRequest("GET", "/users/7", nil), httptest.NewRecorder())
+	ctx.SetParams(map[string]string{"id": "7"})
+	if err := ctx.BindRequest(&req); err != nil {
+		t.Fatal(err)
+	}
+	if req.Page != 1 || req.ID != 7 || req.Tenant != "public" {
+		t.Fatalf("got %+v", req)
+	}
+}
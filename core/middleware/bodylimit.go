@@ -0,0 +1,13 @@
+package middleware
+
+import (
+	"github.com/betNevS/easyweb/core"
+)
+
+// BodyLimit override the global maximum body size for the routes it is applied to
+func BodyLimit(n int64) core.ControllerHandler {
+	return func(ctx *core.Context) error {
+		ctx.SetMaxBodySize(n)
+		return ctx.Next()
+	}
+}
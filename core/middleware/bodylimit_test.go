@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/betNevS/easyweb/core"
+)
+
+func TestBodyLimit(t *testing.T) {
+	c := core.New()
+	c.SetMaxBodySize(10)
+	c.Post("/global", echoBody)
+	c.Post("/raised", BodyLimit(100), echoBody)
+	c.Post("/lowered", BodyLimit(5), echoBody)
+	c.Post("/unlimited", BodyLimit(0), echoBody)
+
+	cases := []struct {
+		path string
+		size int
+		code int
+	}{
+		{path: "/global", size: 10, code: http.StatusOK},
+		{path: "/global", size: 11, code: http.StatusRequestEntityTooLarge},
+		{path: "/raised", size: 50, code: http.StatusOK},
+		{path: "/raised", size: 101, code: http.StatusRequestEntityTooLarge},
+		{path: "/lowered", size: 6, code: http.StatusRequestEntityTooLarge},
+		{path: "/unlimited", size: 1000, code: http.StatusOK},
+	}
+	for _, tc := range cases {
+		body := strings.Repeat("a", tc.size)
+		w := httptest.NewRecorder()
+		c.ServeHTTP(w, httptest.NewRequest(http.MethodPost, tc.path, strings.NewReader(body)))
+		if w.Code != tc.code {
+			t.Fatalf("%s with %d bytes: got %d %q, want %d", tc.path, tc.size, w.Code, w.Body.String(), tc.code)
+		}
+		if tc.code == http.StatusOK && w.Body.String() != body {
+			t.Fatalf("%s: got %d bytes, want %d", tc.path, w.Body.Len(), tc.size)
+		}
+	}
+}
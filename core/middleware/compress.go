@@ -0,0 +1,257 @@
+package middleware
+
+import (
+	"bufio"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/betNevS/easyweb/core"
+)
+
+type CompressConfig struct {
+	// gzip/zlib 压缩级别，取值同 compress/gzip，0 即 gzip.NoCompression；Compress() 使用 gzip.DefaultCompression
+	Level int
+	// 小于该字节数的响应不压缩，默认 1024
+	MinLength int
+	// 不压缩的 Content-Type 前缀，默认是图片、音视频和压缩包等已压缩的类型
+	ExcludedContentTypes []string
+}
+
+var defaultExcludedContentTypes = []string{
+	"image/png", "image/jpeg", "image/gif", "image/webp", "image/avif",
+	"video/", "audio/", "font/woff",
+	"application/zip", "application/gzip", "application/x-gzip", "application/zstd",
+	"application/x-7z-compressed", "application/x-rar-compressed", "application/x-bzip2",
+}
+
+func Compress() core.ControllerHandler {
+	return CompressWithConfig(CompressConfig{Level: gzip.DefaultCompression})
+}
+
+// CompressWithConfig compress the response with gzip or deflate as negotiated by
+// Accept-Encoding, responses are buffered until MinLength bytes or a flush decide whether
+// to compress, flushed responses such as streams and server-sent events stay streaming; it
+// panics on an invalid Level
+func CompressWithConfig(config CompressConfig) core.ControllerHandler {
+	if config.Level < gzip.HuffmanOnly || config.Level > gzip.BestCompression {
+		panic(fmt.Sprintf("easyweb compress: invalid level %d", config.Level))
+	}
+	if config.MinLength <= 0 {
+		config.MinLength = 1024
+	}
+	if config.ExcludedContentTypes == nil {
+		config.ExcludedContentTypes = defaultExcludedContentTypes
+	}
+
+	pools := map[string]*sync.Pool{
+		"gzip": {New: func() interface{} {
+			w, _ := gzip.NewWriterLevel(nil, config.Level)
+			return w
+		}},
+		"deflate": {New: func() interface{} {
+			w, _ := zlib.NewWriterLevel(nil, config.Level)
+			return w
+		}},
+	}
+
+	return func(ctx *core.Context) error {
+		request := ctx.GetRequest()
+		origin := ctx.GetResponse()
+		origin.Header().Add("Vary", "Accept-Encoding")
+
+		encoding := negotiateEncoding(request.Header.Get("Accept-Encoding"))
+		if encoding == "" || request.Method == http.MethodHead || request.Header.Get("Range") != "" || ctx.IsWebSocket() {
+			return ctx.Next()
+		}
+
+		cw := &compressWriter{
+			ResponseWriter: origin,
+			config:         &config,
+			encoding:       encoding,
+			pool:           pools[encoding],
+		}
+		ctx.SetResponse(cw)
+
+		err := ctx.Next()
+
+		ctx.WriteMutex().Lock()
+		defer ctx.WriteMutex().Unlock()
+		// 提交缓冲的状态码，出错且未写出时交给错误处理
+		if inner, ok := ctx.GetResponse().(interface{ WriteHeaderNow() }); ok && err == nil && !ctx.HasStopped() {
+			inner.WriteHeaderNow()
+		}
+		ctx.SetResponse(origin)
+		cw.close()
+		return err
+	}
+}
+
+// negotiateEncoding pick gzip or deflate from Accept-Encoding, gzip on a tie
+func negotiateEncoding(accept string) string {
+	q := map[string]float64{}
+	for _, part := range strings.Split(accept, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		weight := 1.0
+		if k, v, ok := strings.Cut(strings.TrimSpace(params), "="); ok && strings.TrimSpace(k) == "q" {
+			if f, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil {
+				weight = f
+			}
+		}
+		q[name] = weight
+	}
+
+	best, bestQ := "", 0.0
+	for _, encoding := range []string{"gzip", "deflate"} {
+		weight, ok := q[encoding]
+		if !ok {
+			weight, ok = q["*"]
+		}
+		if ok && weight > bestQ {
+			best, bestQ = encoding, weight
+		}
+	}
+	return best
+}
+
+type compressWriter struct {
+	http.ResponseWriter
+	config   *CompressConfig
+	encoding string
+	pool     *sync.Pool
+
+	status  int
+	buf     []byte
+	decided bool
+	writer  io.WriteCloser
+}
+
+func (w *compressWriter) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+}
+
+func (w *compressWriter) Write(b []byte) (int, error) {
+	if !w.decided {
+		w.buf = append(w.buf, b...)
+		if len(w.buf) < w.config.MinLength {
+			return len(b), nil
+		}
+		if err := w.decide(true); err != nil {
+			return 0, err
+		}
+		return len(b), nil
+	}
+	if w.writer != nil {
+		return w.writer.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush decide on compression without waiting for MinLength, so streamed responses are not
+// held back, and flush the compressor and the connection
+func (w *compressWriter) Flush() {
+	if !w.decided {
+		w.decide(true)
+	}
+	if f, ok := w.writer.(interface{ Flush() error }); ok {
+		f.Flush()
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response writer does not implement http.Hijacker")
+	}
+	return h.Hijack()
+}
+
+// decide send the status and headers, compressing the body when allowed and wanted, then
+// write out the buffered bytes
+func (w *compressWriter) decide(compress bool) error {
+	w.decided = true
+	header := w.Header()
+	if len(w.buf) > 0 && header.Get("Content-Type") == "" {
+		// 压缩后无法再由 net/http 嗅探类型
+		header.Set("Content-Type", http.DetectContentType(w.buf))
+	}
+
+	if compress && w.compressible() {
+		header.Set("Content-Encoding", w.encoding)
+		header.Del("Content-Length")
+		switch cw := w.pool.Get().(type) {
+		case *gzip.Writer:
+			cw.Reset(w.ResponseWriter)
+			w.writer = cw
+		case *zlib.Writer:
+			cw.Reset(w.ResponseWriter)
+			w.writer = cw
+		}
+	}
+
+	if w.status != 0 {
+		w.ResponseWriter.WriteHeader(w.status)
+	}
+	if len(w.buf) == 0 {
+		return nil
+	}
+	buf := w.buf
+	w.buf = nil
+	if w.writer != nil {
+		_, err := w.writer.Write(buf)
+		return err
+	}
+	_, err := w.ResponseWriter.Write(buf)
+	return err
+}
+
+func (w *compressWriter) compressible() bool {
+	switch w.status {
+	case http.StatusNoContent, http.StatusNotModified, http.StatusPartialContent:
+		return false
+	}
+	header := w.Header()
+	if header.Get("Content-Encoding") != "" || header.Get("Content-Range") != "" {
+		return false
+	}
+	contentType := strings.ToLower(header.Get("Content-Type"))
+	for _, excluded := range w.config.ExcludedContentTypes {
+		if strings.HasPrefix(contentType, excluded) {
+			return false
+		}
+	}
+	return true
+}
+
+// close write out a response that stayed below MinLength uncompressed, or finish the
+// compressed stream and return the compressor to the pool
+func (w *compressWriter) close() {
+	if !w.decided {
+		if w.status == 0 && len(w.buf) == 0 {
+			return
+		}
+		w.decide(false)
+		return
+	}
+	if w.writer != nil {
+		w.writer.Close()
+		w.pool.Put(w.writer)
+		w.writer = nil
+	}
+}
@@ -0,0 +1,218 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/betNevS/easyweb/core"
+)
+
+func decodeBody(encoding string, body io.Reader) (string, error) {
+	var r io.Reader
+	var err error
+	switch encoding {
+	case "gzip":
+		r, err = gzip.NewReader(body)
+	case "deflate":
+		r, err = zlib.NewReader(body)
+	default:
+		r = body
+	}
+	if err != nil {
+		return "", err
+	}
+	b, err := io.ReadAll(r)
+	return string(b), err
+}
+
+func TestCompress(t *testing.T) {
+	large := strings.Repeat("compress me ", 200)
+
+	c := core.New()
+	c.Use(Compress())
+	c.Get("/large", func(ctx *core.Context) error {
+		ctx.Text("%s", large)
+		return nil
+	})
+	c.Get("/small", func(ctx *core.Context) error {
+		ctx.Text("small")
+		return nil
+	})
+	c.Get("/png", func(ctx *core.Context) error {
+		ctx.SetHeader("Content-Type", "image/png")
+		ctx.Text("%s", large)
+		return nil
+	})
+	c.Get("/empty", func(ctx *core.Context) error {
+		ctx.SetStatus(http.StatusNoContent)
+		return nil
+	})
+	c.Head("/large", func(ctx *core.Context) error {
+		ctx.Text("%s", large)
+		return nil
+	})
+
+	cases := []struct {
+		name     string
+		method   string
+		path     string
+		headers  map[string]string
+		encoding string
+		code     int
+	}{
+		{name: "gzip", path: "/large", headers: map[string]string{"Accept-Encoding": "gzip, deflate"}, encoding: "gzip"},
+		{name: "deflate", path: "/large", headers: map[string]string{"Accept-Encoding": "deflate"}, encoding: "deflate"},
+		{name: "q values", path: "/large", headers: map[string]string{"Accept-Encoding": "gzip;q=0.5, deflate;q=0.8"}, encoding: "deflate"},
+		{name: "gzip refused", path: "/large", headers: map[string]string{"Accept-Encoding": "gzip;q=0, *"}, encoding: "deflate"},
+		{name: "no accept-encoding", path: "/large"},
+		{name: "below min length", path: "/small", headers: map[string]string{"Accept-Encoding": "gzip"}},
+		{name: "excluded type", path: "/png", headers: map[string]string{"Accept-Encoding": "gzip"}},
+		{name: "range", path: "/large", headers: map[string]string{"Accept-Encoding": "gzip", "Range": "bytes=0-10"}},
+		{name: "head", method: http.MethodHead, path: "/large", headers: map[string]string{"Accept-Encoding": "gzip"}},
+		{name: "no content", path: "/empty", headers: map[string]string{"Accept-Encoding": "gzip"}, code: http.StatusNoContent},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			method := tc.method
+			if method == "" {
+				method = http.MethodGet
+			}
+			code := tc.code
+			if code == 0 {
+				code = http.StatusOK
+			}
+			r := httptest.NewRequest(method, tc.path, nil)
+			for k, v := range tc.headers {
+				r.Header.Set(k, v)
+			}
+			w := httptest.NewRecorder()
+			c.ServeHTTP(w, r)
+
+			if w.Code != code {
+				t.Fatalf("got %d, want %d", w.Code, code)
+			}
+			if got := w.Header().Get("Content-Encoding"); got != tc.encoding {
+				t.Fatalf("got Content-Encoding %q, want %q", got, tc.encoding)
+			}
+			if w.Header().Get("Vary") != "Accept-Encoding" {
+				t.Fatalf("got Vary %q", w.Header().Get("Vary"))
+			}
+			if tc.encoding != "" && w.Header().Get("Content-Length") != "" {
+				t.Fatal("Content-Length kept on a compressed response")
+			}
+			if method == http.MethodHead || code == http.StatusNoContent {
+				return
+			}
+			body, err := decodeBody(tc.encoding, w.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			switch tc.path {
+			case "/small":
+				if body != "small" {
+					t.Fatalf("got %q", body)
+				}
+			default:
+				if body != large {
+					t.Fatalf("got %d bytes, want %d", len(body), len(large))
+				}
+			}
+		})
+	}
+}
+
+func TestCompressStreaming(t *testing.T) {
+	next := make(chan struct{})
+	c := core.New()
+	c.Use(Compress())
+	c.Get("/stream", func(ctx *core.Context) error {
+		ctx.Stream(func(w io.Writer) bool {
+			w.Write([]byte("first"))
+			return false
+		})
+		<-next
+		ctx.Stream(func(w io.Writer) bool {
+			w.Write([]byte("second"))
+			return false
+		})
+		return nil
+	})
+	server := httptest.NewServer(c)
+	defer server.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"/stream", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.Header.Get("Content-Encoding") != "gzip" {
+		t.Fatalf("got Content-Encoding %q", resp.Header.Get("Content-Encoding"))
+	}
+
+	// 第一块在 handler 结束前就要能解压出来
+	zr, err := gzip.NewReader(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := make([]byte, 5)
+	if _, err := io.ReadFull(zr, first); err != nil || string(first) != "first" {
+		t.Fatalf("got %q %v", first, err)
+	}
+	close(next)
+	rest, err := io.ReadAll(zr)
+	if err != nil || string(rest) != "second" {
+		t.Fatalf("got %q %v", rest, err)
+	}
+}
+
+func TestCompressPooledWriters(t *testing.T) {
+	c := core.New()
+	c.Use(CompressWithConfig(CompressConfig{Level: gzip.BestSpeed, MinLength: 1}))
+	c.Get("/echo", func(ctx *core.Context) error {
+		msg, _ := ctx.QueryString("msg", "")
+		ctx.Text("%s", strings.Repeat(msg, 100))
+		return nil
+	})
+
+	// 复用的压缩器不能带上一次响应的数据
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			encoding := []string{"gzip", "deflate"}[i%2]
+			msg := strings.Repeat(string(rune('a'+i%26)), i+1)
+			r := httptest.NewRequest(http.MethodGet, "/echo?msg="+msg, nil)
+			r.Header.Set("Accept-Encoding", encoding)
+			w := httptest.NewRecorder()
+			c.ServeHTTP(w, r)
+			if got := w.Header().Get("Content-Encoding"); got != encoding {
+				t.Errorf("got Content-Encoding %q, want %q", got, encoding)
+				return
+			}
+			if body, err := decodeBody(encoding, w.Body); err != nil || body != strings.Repeat(msg, 100) {
+				t.Errorf("request %d: got %d bytes, %v", i, len(body), err)
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestCompressInvalidLevel(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected a panic for an invalid level")
+		}
+	}()
+	CompressWithConfig(CompressConfig{Level: 42})
+}
@@ -11,12 +11,12 @@ func Cost() core.ControllerHandler {
 	return func(ctx *core.Context) error {
 		start := time.Now()
 
-		ctx.Next()
+		err := ctx.Next()
 
 		end := time.Now()
 		cost := end.Sub(start)
 
 		log.Printf("api uri: %v, cost: %v", ctx.GetRequest().RequestURI, cost.Seconds())
-		return nil
+		return err
 	}
 }
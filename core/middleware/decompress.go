@@ -0,0 +1,86 @@
+package middleware
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/betNevS/easyweb/core"
+)
+
+const defaultMaxDecompressedSize = 10 << 20
+
+// Decompress inflate gzip and deflate request bodies before binding, a body growing past
+// maxSize bytes fails with core.ErrBodyTooLarge, 0 means 10MB; a BodyLimit before it limits
+// the compressed bytes, one after it the decompressed bytes
+func Decompress(maxSize int64) core.ControllerHandler {
+	if maxSize <= 0 {
+		maxSize = defaultMaxDecompressedSize
+	}
+
+	return func(ctx *core.Context) error {
+		request := ctx.GetRequest()
+		encoding := strings.ToLower(strings.TrimSpace(request.Header.Get("Content-Encoding")))
+		if encoding == "" || encoding == "identity" || request.Body == nil {
+			return ctx.Next()
+		}
+
+		var reader io.ReadCloser
+		var err error
+		switch encoding {
+		case "gzip", "x-gzip":
+			reader, err = gzip.NewReader(request.Body)
+		case "deflate":
+			reader, err = newDeflateReader(request.Body)
+		default:
+			ctx.SetStatus(http.StatusUnsupportedMediaType).JSON("unsupported content encoding: " + encoding)
+			return nil
+		}
+		if err != nil {
+			ctx.SetStatus(http.StatusBadRequest).JSON("invalid " + encoding + " body")
+			return nil
+		}
+
+		ctx.SetBody(&decompressReader{
+			Reader: core.NewLimitReader(reader, maxSize, fmt.Errorf("%w: decompressed limit is %d bytes", core.ErrBodyTooLarge, maxSize)),
+			reader: reader,
+			body:   request.Body,
+		})
+		request.Header.Del("Content-Encoding")
+		request.Header.Del("Content-Length")
+		request.ContentLength = -1
+
+		return ctx.Next()
+	}
+}
+
+// newDeflateReader accept zlib wrapped deflate as HTTP specifies, and raw deflate some clients send
+func newDeflateReader(body io.Reader) (io.ReadCloser, error) {
+	br := bufio.NewReader(body)
+	header, err := br.Peek(2)
+	if err != nil {
+		return nil, err
+	}
+	// zlib header: CM is 8 and the first two bytes are a multiple of 31
+	if header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 {
+		return zlib.NewReader(br)
+	}
+	return flate.NewReader(br), nil
+}
+
+// decompressReader cap the decompressed size to defend against zip bombs
+type decompressReader struct {
+	io.Reader
+	reader io.ReadCloser
+	body   io.Closer
+}
+
+func (r *decompressReader) Close() error {
+	r.reader.Close()
+	return r.body.Close()
+}
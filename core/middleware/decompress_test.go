@@ -0,0 +1,123 @@
+package middleware
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/betNevS/easyweb/core"
+)
+
+func gzipBytes(data string) []byte {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	w.Write([]byte(data))
+	w.Close()
+	return buf.Bytes()
+}
+
+func zlibBytes(data string) []byte {
+	var buf bytes.Buffer
+	w := zlib.NewWriter(&buf)
+	w.Write([]byte(data))
+	w.Close()
+	return buf.Bytes()
+}
+
+func flateBytes(data string) []byte {
+	var buf bytes.Buffer
+	w, _ := flate.NewWriter(&buf, flate.DefaultCompression)
+	w.Write([]byte(data))
+	w.Close()
+	return buf.Bytes()
+}
+
+// echoBody answer the request body, 413 for core.ErrBodyTooLarge and 400 for other read errors
+func echoBody(ctx *core.Context) error {
+	data, err := ctx.GetRawData()
+	switch {
+	case errors.Is(err, core.ErrBodyTooLarge):
+		ctx.SetStatus(http.StatusRequestEntityTooLarge).Text("%s", err)
+	case err != nil:
+		ctx.SetStatus(http.StatusBadRequest).Text("%s", err)
+	default:
+		ctx.Text("%s", data)
+	}
+	return nil
+}
+
+func TestDecompress(t *testing.T) {
+	c := core.New()
+	c.Post("/echo", Decompress(64), echoBody)
+
+	payload := strings.Repeat("a", 64)
+	corrupt := gzipBytes(payload)
+	// 破坏 CRC32，数据恰好读满上限时也要报错
+	corrupt[len(corrupt)-8] ^= 0xff
+
+	cases := []struct {
+		name     string
+		encoding string
+		body     []byte
+		code     int
+		want     string
+	}{
+		{name: "gzip", encoding: "gzip", body: gzipBytes("hello"), code: http.StatusOK, want: "hello"},
+		{name: "x-gzip", encoding: "x-gzip", body: gzipBytes("hello"), code: http.StatusOK, want: "hello"},
+		{name: "zlib deflate", encoding: "deflate", body: zlibBytes("hello"), code: http.StatusOK, want: "hello"},
+		{name: "raw deflate", encoding: "deflate", body: flateBytes("hello"), code: http.StatusOK, want: "hello"},
+		{name: "identity", encoding: "identity", body: []byte("hello"), code: http.StatusOK, want: "hello"},
+		{name: "exactly the limit", encoding: "gzip", body: gzipBytes(payload), code: http.StatusOK, want: payload},
+		{name: "over the limit", encoding: "gzip", body: gzipBytes(payload + "a"), code: http.StatusRequestEntityTooLarge},
+		{name: "corrupt checksum at the limit", encoding: "gzip", body: corrupt, code: http.StatusBadRequest},
+		{name: "unknown encoding", encoding: "br", body: []byte("hello"), code: http.StatusUnsupportedMediaType},
+		{name: "empty gzip", encoding: "gzip", body: nil, code: http.StatusBadRequest},
+		{name: "invalid gzip", encoding: "gzip", body: []byte("not gzip"), code: http.StatusBadRequest},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/echo", bytes.NewReader(tc.body))
+			r.Header.Set("Content-Encoding", tc.encoding)
+			w := httptest.NewRecorder()
+			c.ServeHTTP(w, r)
+			if w.Code != tc.code {
+				t.Fatalf("got %d %q, want %d", w.Code, w.Body.String(), tc.code)
+			}
+			if tc.want != "" && w.Body.String() != tc.want {
+				t.Fatalf("got %q, want %q", w.Body.String(), tc.want)
+			}
+		})
+	}
+}
+
+func TestDecompressWithBodyLimit(t *testing.T) {
+	c := core.New()
+	// BodyLimit 在前限制压缩后的字节数，在后限制解压后的字节数
+	c.Post("/compressed", BodyLimit(100), Decompress(0), echoBody)
+	c.Post("/decompressed", Decompress(0), BodyLimit(100), echoBody)
+
+	payload := strings.Repeat("a", 1000)
+	for path, code := range map[string]int{
+		"/compressed":   http.StatusOK,
+		"/decompressed": http.StatusRequestEntityTooLarge,
+	} {
+		r := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(gzipBytes(payload)))
+		r.Header.Set("Content-Encoding", "gzip")
+		w := httptest.NewRecorder()
+		c.ServeHTTP(w, r)
+		if w.Code != code {
+			t.Fatalf("%s: got %d %q, want %d", path, w.Code, w.Body.String(), code)
+		}
+		if code == http.StatusOK && w.Body.String() != payload {
+			t.Fatalf("%s: got %d bytes", path, w.Body.Len())
+		}
+	}
+}
@@ -13,7 +13,6 @@ func Recovery() core.ControllerHandler {
 				ctx.SetStatus(http.StatusInternalServerError).JSON("INTERNAL ERROR")
 			}
 		}()
-		ctx.Next()
-		return nil
+		return ctx.Next()
 	}
 }
@@ -1,20 +1,60 @@
 package middleware
 
 import (
+	"bufio"
+	"bytes"
 	"context"
-	"fmt"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/betNevS/easyweb/core"
 )
 
+type TimeoutConfig struct {
+	Timeout time.Duration
+	// StatusCode sent on timeout, default http.StatusServiceUnavailable
+	StatusCode int
+	// Message sent on timeout, default "time out"
+	Message string
+}
+
 func Timeout(d time.Duration) core.ControllerHandler {
+	return TimeoutWithConfig(TimeoutConfig{Timeout: d})
+}
+
+// TimeoutWithConfig run the rest of the handler chain against a buffered writer, the buffered
+// response is copied to the client on success and dropped on timeout; once the handler flushes
+// or hijacks the connection, as streams, server-sent events and websocket upgrades do, the
+// response goes straight to the client and the timeout no longer applies
+func TimeoutWithConfig(config TimeoutConfig) core.ControllerHandler {
+	if config.StatusCode == 0 {
+		config.StatusCode = http.StatusServiceUnavailable
+	}
+	if config.Message == "" {
+		config.Message = "time out"
+	}
+
 	return func(ctx *core.Context) error {
-		finish := make(chan struct{}, 1)
+		finish := make(chan error, 1)
 		panicChan := make(chan interface{}, 1)
 
-		ctxTimeout, cancel := context.WithTimeout(ctx.BaseContext(), d)
+		prev := ctx.BaseContext()
+		cancelCtx, cancel := context.WithCancel(prev)
 		defer cancel()
+		ctxTimeout := &timeoutContext{Context: cancelCtx}
+
+		timer := time.NewTimer(config.Timeout)
+		defer timer.Stop()
+
+		origin := ctx.GetResponse()
+		buffer := newBufferedWriter(origin, func() { timer.Stop() })
+		ctx.WithContext(ctxTimeout)
+		ctx.SetResponse(buffer)
 
 		go func() {
 			defer func() {
@@ -23,20 +63,173 @@ func Timeout(d time.Duration) core.ControllerHandler {
 				}
 			}()
 
-			ctx.Next()
+			err := ctx.Next()
+			// 只把状态码提交到缓冲，不触发 Flush
+			if w, ok := ctx.GetResponse().(interface{ WriteHeaderNow() }); ok {
+				w.WriteHeaderNow()
+			}
 
-			finish <- struct{}{}
+			finish <- err
 		}()
 
-		select {
-		case <-finish:
-			fmt.Println("perfect finish")
-		case p := <-panicChan:
-			fmt.Println(p)
-			ctx.ExecPanic()
-		case <-ctxTimeout.Done():
-			ctx.ExecTimeout()
+		for {
+			select {
+			case err := <-finish:
+				// 恢复原 context，避免 cancel 后外层中间件看到 context canceled
+				ctx.WithContext(prev)
+				ctx.SetResponse(origin)
+				buffer.finish()
+				return err
+			case p := <-panicChan:
+				log.Println(p)
+				ctx.WithContext(prev)
+				ctx.SetResponse(origin)
+				ctx.ExecPanic()
+				return nil
+			case <-timer.C:
+				ctx.WriteMutex().Lock()
+				if !buffer.timeout() {
+					// 已经开始流式输出，继续等待 handler 结束
+					ctx.WriteMutex().Unlock()
+					continue
+				}
+				ctx.SetHasStopped()
+				origin.WriteHeader(config.StatusCode)
+				origin.Write([]byte(config.Message))
+				ctx.WriteMutex().Unlock()
+
+				atomic.StoreInt32(&ctxTimeout.timedOut, 1)
+				cancel()
+				return nil
+			}
 		}
-		return nil
+	}
+}
+
+// timeoutContext is cancelled by Timeout instead of carrying a deadline, so that a streaming
+// handler can outlive it, it still reports context.DeadlineExceeded once the timeout fired
+type timeoutContext struct {
+	context.Context
+	timedOut int32
+}
+
+func (c *timeoutContext) Err() error {
+	if atomic.LoadInt32(&c.timedOut) == 1 {
+		return context.DeadlineExceeded
+	}
+	return c.Context.Err()
+}
+
+// bufferedWriter hold the response of a handler running under Timeout until it finishes,
+// flushes or hijacks the connection
+type bufferedWriter struct {
+	origin   http.ResponseWriter
+	onStream func()
+
+	mu     sync.Mutex
+	header http.Header
+	status int
+	body   bytes.Buffer
+	// streaming 后直接写到 origin，timedOut 后缓冲被丢弃
+	streaming bool
+	timedOut  bool
+}
+
+func newBufferedWriter(origin http.ResponseWriter, onStream func()) *bufferedWriter {
+	return &bufferedWriter{origin: origin, onStream: onStream, header: http.Header{}}
+}
+
+func (w *bufferedWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *bufferedWriter) WriteHeader(code int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.streaming {
+		w.origin.WriteHeader(code)
+		return
+	}
+	if w.status == 0 {
+		w.status = code
+	}
+}
+
+func (w *bufferedWriter) Write(b []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.streaming {
+		return w.origin.Write(b)
+	}
+	return w.body.Write(b)
+}
+
+// Flush send the buffered response and switch to writing straight to the client
+func (w *bufferedWriter) Flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timedOut {
+		return
+	}
+	if !w.streaming {
+		w.stream()
+		w.copyTo(w.origin)
+	}
+	if f, ok := w.origin.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *bufferedWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timedOut {
+		return nil, nil, core.ErrResponseStopped
+	}
+	h, ok := w.origin.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response writer does not implement http.Hijacker")
+	}
+	w.stream()
+	return h.Hijack()
+}
+
+func (w *bufferedWriter) stream() {
+	if !w.streaming {
+		w.streaming = true
+		w.onStream()
+	}
+}
+
+// timeout drop the buffered response, it reports false when the response is already streaming
+func (w *bufferedWriter) timeout() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.streaming {
+		return false
+	}
+	w.timedOut = true
+	return true
+}
+
+// finish copy the buffered response to the client
+func (w *bufferedWriter) finish() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.copyTo(w.origin)
+}
+
+// copyTo write out the headers, status and body buffered so far
+func (w *bufferedWriter) copyTo(dst http.ResponseWriter) {
+	for k, v := range w.header {
+		dst.Header()[k] = v
+	}
+	if w.status != 0 {
+		dst.WriteHeader(w.status)
+		w.status = 0
+	}
+	if w.body.Len() > 0 {
+		dst.Write(w.body.Bytes())
+		w.body.Reset()
 	}
 }
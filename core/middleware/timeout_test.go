@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/betNevS/easyweb/core"
+)
+
+func TestTimeout(t *testing.T) {
+	c := core.New()
+	c.Use(Timeout(50 * time.Millisecond))
+	c.Get("/fast", func(ctx *core.Context) error {
+		ctx.Text("ok")
+		return nil
+	})
+	errChan := make(chan error, 1)
+	c.Get("/slow", func(ctx *core.Context) error {
+		<-ctx.Done()
+		errChan <- ctx.Err()
+		ctx.Text("late")
+		return nil
+	})
+
+	w := httptest.NewRecorder()
+	c.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/fast", nil))
+	if w.Code != http.StatusOK || w.Body.String() != "ok" {
+		t.Fatalf("got %d %q", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	c.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/slow", nil))
+	if w.Code != http.StatusServiceUnavailable || w.Body.String() != "time out" {
+		t.Fatalf("got %d %q", w.Code, w.Body.String())
+	}
+	if err := <-errChan; !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestTimeoutStreaming(t *testing.T) {
+	c := core.New()
+	c.Use(Timeout(50 * time.Millisecond))
+	c.Get("/events", func(ctx *core.Context) error {
+		if err := ctx.SSEvent(core.SSEvent{Data: "first"}); err != nil {
+			return err
+		}
+		time.Sleep(100 * time.Millisecond)
+		if ctx.Err() != nil {
+			t.Errorf("handler context done while streaming: %v", ctx.Err())
+		}
+		return ctx.SSEvent(core.SSEvent{Data: "second"})
+	})
+
+	w := httptest.NewRecorder()
+	c.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/events", nil))
+	body := w.Body.String()
+	if w.Code != http.StatusOK || !strings.Contains(body, "data: first") || !strings.Contains(body, "data: second") {
+		t.Fatalf("got %d %q", w.Code, body)
+	}
+	if !w.Flushed {
+		t.Fatal("expected the events to be flushed")
+	}
+}
@@ -0,0 +1,42 @@
+package core
+
+import (
+	"log"
+	"sync/atomic"
+)
+
+const (
+	DebugMode   = "debug"
+	ReleaseMode = "release"
+)
+
+var debugFlag int32 = 1
+
+// SetMode switch the framework between DebugMode and ReleaseMode
+func SetMode(mode string) {
+	switch mode {
+	case DebugMode:
+		atomic.StoreInt32(&debugFlag, 1)
+	case ReleaseMode:
+		atomic.StoreInt32(&debugFlag, 0)
+	default:
+		panic("easyweb mode unknown: " + mode)
+	}
+}
+
+func Mode() string {
+	if IsDebugging() {
+		return DebugMode
+	}
+	return ReleaseMode
+}
+
+func IsDebugging() bool {
+	return atomic.LoadInt32(&debugFlag) == 1
+}
+
+func debugPrint(format string, values ...interface{}) {
+	if IsDebugging() {
+		log.Printf("[easyweb-debug] "+format, values...)
+	}
+}
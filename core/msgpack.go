@@ -0,0 +1,681 @@
+package core
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"time"
+)
+
+const tagMsgPack = "msgpack"
+
+// 与 encoding/json 相同的最大嵌套深度
+const msgPackMaxDepth = 10000
+
+var (
+	errMsgPackShort   = errors.New("msgpack: unexpected end of data")
+	errMsgPackTooDeep = errors.New("msgpack: exceeded max depth")
+)
+
+// msgPackCodec is a MessagePack implementation on the standard library, structs are encoded as maps
+// keyed by the `msgpack` tag, then the `json` tag, then the field name, time.Time as the timestamp extension
+type msgPackCodec struct{}
+
+func (msgPackCodec) Name() string {
+	return "msgpack"
+}
+
+func (msgPackCodec) ContentType() string {
+	return MIMEMsgPack
+}
+
+func (msgPackCodec) Marshal(obj interface{}) ([]byte, error) {
+	var e msgPackEncoder
+	if err := e.encode(reflect.ValueOf(obj)); err != nil {
+		return nil, err
+	}
+	return e.buf.Bytes(), nil
+}
+
+func (msgPackCodec) Unmarshal(data []byte, obj interface{}) error {
+	rv := reflect.ValueOf(obj)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return errors.New("msgpack: decode target must be a non-nil pointer")
+	}
+	d := msgPackDecoder{data: data}
+	val, err := d.decode()
+	if err != nil {
+		return err
+	}
+	if d.pos != len(d.data) {
+		return errors.New("msgpack: trailing data")
+	}
+	return msgPackAssign(rv.Elem(), val)
+}
+
+type msgPackEncoder struct {
+	buf bytes.Buffer
+}
+
+func (e *msgPackEncoder) writeByte(b byte) {
+	e.buf.WriteByte(b)
+}
+
+func (e *msgPackEncoder) writeUint(prefix byte, n uint64, size int) {
+	e.buf.WriteByte(prefix)
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], n)
+	e.buf.Write(b[8-size:])
+}
+
+func (e *msgPackEncoder) encode(v reflect.Value) error {
+	if !v.IsValid() {
+		e.writeByte(0xc0)
+		return nil
+	}
+	if v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			e.writeByte(0xc0)
+			return nil
+		}
+		return e.encode(v.Elem())
+	}
+	if v.Type() == timeType {
+		t := v.Interface().(time.Time)
+		e.writeByte(0xc7)
+		e.writeByte(12)
+		e.writeByte(0xff)
+		var b [12]byte
+		binary.BigEndian.PutUint32(b[:4], uint32(t.Nanosecond()))
+		binary.BigEndian.PutUint64(b[4:], uint64(t.Unix()))
+		e.buf.Write(b[:])
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		if v.Bool() {
+			e.writeByte(0xc3)
+		} else {
+			e.writeByte(0xc2)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		e.encodeInt(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		e.encodeUint(v.Uint())
+	case reflect.Float32:
+		e.writeUint(0xca, uint64(math.Float32bits(float32(v.Float()))), 4)
+	case reflect.Float64:
+		e.writeUint(0xcb, math.Float64bits(v.Float()), 8)
+	case reflect.String:
+		e.encodeString(v.String())
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			e.writeByte(0xc0)
+			return nil
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			b := make([]byte, v.Len())
+			reflect.Copy(reflect.ValueOf(b), v)
+			e.encodeBytes(b)
+			return nil
+		}
+		e.encodeLen(v.Len(), 0x90, 0x0f, 0xdc, 0xdd)
+		for i := 0; i < v.Len(); i++ {
+			if err := e.encode(v.Index(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		if v.IsNil() {
+			e.writeByte(0xc0)
+			return nil
+		}
+		e.encodeLen(v.Len(), 0x80, 0x0f, 0xde, 0xdf)
+		iter := v.MapRange()
+		for iter.Next() {
+			if err := e.encode(iter.Key()); err != nil {
+				return err
+			}
+			if err := e.encode(iter.Value()); err != nil {
+				return err
+			}
+		}
+	case reflect.Struct:
+		fields := msgPackFields(v.Type())
+		values := make([]reflect.Value, 0, len(fields))
+		names := make([]string, 0, len(fields))
+		for _, f := range fields {
+			fv := v.FieldByIndex(f.index)
+			if f.omitEmpty && fv.IsZero() {
+				continue
+			}
+			values = append(values, fv)
+			names = append(names, f.name)
+		}
+		e.encodeLen(len(values), 0x80, 0x0f, 0xde, 0xdf)
+		for i := range values {
+			e.encodeString(names[i])
+			if err := e.encode(values[i]); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("msgpack: unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+func (e *msgPackEncoder) encodeInt(n int64) {
+	switch {
+	case n >= 0:
+		e.encodeUint(uint64(n))
+	case n >= -32:
+		e.writeByte(byte(int8(n)))
+	case n >= math.MinInt8:
+		e.writeUint(0xd0, uint64(n), 1)
+	case n >= math.MinInt16:
+		e.writeUint(0xd1, uint64(n), 2)
+	case n >= math.MinInt32:
+		e.writeUint(0xd2, uint64(n), 4)
+	default:
+		e.writeUint(0xd3, uint64(n), 8)
+	}
+}
+
+func (e *msgPackEncoder) encodeUint(n uint64) {
+	switch {
+	case n < 128:
+		e.writeByte(byte(n))
+	case n <= math.MaxUint8:
+		e.writeUint(0xcc, n, 1)
+	case n <= math.MaxUint16:
+		e.writeUint(0xcd, n, 2)
+	case n <= math.MaxUint32:
+		e.writeUint(0xce, n, 4)
+	default:
+		e.writeUint(0xcf, n, 8)
+	}
+}
+
+func (e *msgPackEncoder) encodeString(s string) {
+	n := len(s)
+	switch {
+	case n < 32:
+		e.writeByte(0xa0 | byte(n))
+	case n <= math.MaxUint8:
+		e.writeUint(0xd9, uint64(n), 1)
+	case n <= math.MaxUint16:
+		e.writeUint(0xda, uint64(n), 2)
+	default:
+		e.writeUint(0xdb, uint64(n), 4)
+	}
+	e.buf.WriteString(s)
+}
+
+func (e *msgPackEncoder) encodeBytes(b []byte) {
+	n := len(b)
+	switch {
+	case n <= math.MaxUint8:
+		e.writeUint(0xc4, uint64(n), 1)
+	case n <= math.MaxUint16:
+		e.writeUint(0xc5, uint64(n), 2)
+	default:
+		e.writeUint(0xc6, uint64(n), 4)
+	}
+	e.buf.Write(b)
+}
+
+// encodeLen write the header of an array or a map, fix holds up to mask elements
+func (e *msgPackEncoder) encodeLen(n int, fix, mask, p16, p32 byte) {
+	switch {
+	case n <= int(mask):
+		e.writeByte(fix | byte(n))
+	case n <= math.MaxUint16:
+		e.writeUint(p16, uint64(n), 2)
+	default:
+		e.writeUint(p32, uint64(n), 4)
+	}
+}
+
+type msgPackField struct {
+	name      string
+	index     []int
+	omitEmpty bool
+}
+
+func msgPackFields(t reflect.Type) []msgPackField {
+	var fields []msgPackField
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" && !field.Anonymous {
+			continue
+		}
+		tag, ok := field.Tag.Lookup(tagMsgPack)
+		if !ok {
+			tag = field.Tag.Get("json")
+		}
+		opts := strings.Split(tag, ",")
+		if opts[0] == "-" {
+			continue
+		}
+		if field.Anonymous && opts[0] == "" && field.Type.Kind() == reflect.Struct {
+			for _, f := range msgPackFields(field.Type) {
+				f.index = append([]int{i}, f.index...)
+				fields = append(fields, f)
+			}
+			continue
+		}
+		if field.PkgPath != "" {
+			continue
+		}
+		name := opts[0]
+		if name == "" {
+			name = field.Name
+		}
+		f := msgPackField{name: name, index: []int{i}}
+		for _, opt := range opts[1:] {
+			if opt == "omitempty" {
+				f.omitEmpty = true
+			}
+		}
+		fields = append(fields, f)
+	}
+	return fields
+}
+
+type msgPackDecoder struct {
+	data  []byte
+	pos   int
+	depth int
+}
+
+// enter track the nesting of arrays and maps, so hostile input can not overflow the stack
+func (d *msgPackDecoder) enter() error {
+	d.depth++
+	if d.depth > msgPackMaxDepth {
+		return errMsgPackTooDeep
+	}
+	return nil
+}
+
+func (d *msgPackDecoder) read(n int) ([]byte, error) {
+	if n < 0 || len(d.data)-d.pos < n {
+		return nil, errMsgPackShort
+	}
+	b := d.data[d.pos : d.pos+n]
+	d.pos += n
+	return b, nil
+}
+
+func (d *msgPackDecoder) readUint(size int) (uint64, error) {
+	b, err := d.read(size)
+	if err != nil {
+		return 0, err
+	}
+	var n uint64
+	for _, c := range b {
+		n = n<<8 | uint64(c)
+	}
+	return n, nil
+}
+
+// decode return nil, bool, int64, uint64, float64, string, []byte, time.Time,
+// []interface{} or map[string]interface{} (map[interface{}]interface{} for non string keys)
+func (d *msgPackDecoder) decode() (interface{}, error) {
+	b, err := d.read(1)
+	if err != nil {
+		return nil, err
+	}
+	c := b[0]
+
+	switch {
+	case c <= 0x7f:
+		return int64(c), nil
+	case c >= 0xe0:
+		return int64(int8(c)), nil
+	case c&0xf0 == 0x80:
+		return d.decodeMap(int(c & 0x0f))
+	case c&0xf0 == 0x90:
+		return d.decodeArray(int(c & 0x0f))
+	case c&0xe0 == 0xa0:
+		s, err := d.read(int(c & 0x1f))
+		return string(s), err
+	}
+
+	switch c {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xc4, 0xc5, 0xc6:
+		n, err := d.readUint(1 << (c - 0xc4))
+		if err != nil {
+			return nil, err
+		}
+		raw, err := d.read(int(n))
+		if err != nil {
+			return nil, err
+		}
+		return append([]byte(nil), raw...), nil
+	case 0xc7, 0xc8, 0xc9:
+		n, err := d.readUint(1 << (c - 0xc7))
+		if err != nil {
+			return nil, err
+		}
+		return d.decodeExt(int(n))
+	case 0xca:
+		n, err := d.readUint(4)
+		return float64(math.Float32frombits(uint32(n))), err
+	case 0xcb:
+		n, err := d.readUint(8)
+		return math.Float64frombits(n), err
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		return d.readUint(1 << (c - 0xcc))
+	case 0xd0:
+		n, err := d.readUint(1)
+		return int64(int8(n)), err
+	case 0xd1:
+		n, err := d.readUint(2)
+		return int64(int16(n)), err
+	case 0xd2:
+		n, err := d.readUint(4)
+		return int64(int32(n)), err
+	case 0xd3:
+		n, err := d.readUint(8)
+		return int64(n), err
+	case 0xd4, 0xd5, 0xd6, 0xd7, 0xd8:
+		return d.decodeExt(1 << (c - 0xd4))
+	case 0xd9, 0xda, 0xdb:
+		n, err := d.readUint(1 << (c - 0xd9))
+		if err != nil {
+			return nil, err
+		}
+		s, err := d.read(int(n))
+		return string(s), err
+	case 0xdc, 0xdd:
+		n, err := d.readUint(2 << (c - 0xdc))
+		if err != nil {
+			return nil, err
+		}
+		return d.decodeArray(int(n))
+	case 0xde, 0xdf:
+		n, err := d.readUint(2 << (c - 0xde))
+		if err != nil {
+			return nil, err
+		}
+		return d.decodeMap(int(n))
+	}
+	return nil, fmt.Errorf("msgpack: invalid code 0x%x", c)
+}
+
+func (d *msgPackDecoder) decodeArray(n int) (interface{}, error) {
+	// every element takes at least one byte, so a forged length can not allocate much
+	if n > len(d.data)-d.pos {
+		return nil, errMsgPackShort
+	}
+	if err := d.enter(); err != nil {
+		return nil, err
+	}
+	defer func() { d.depth-- }()
+
+	ret := make([]interface{}, n)
+	for i := range ret {
+		v, err := d.decode()
+		if err != nil {
+			return nil, err
+		}
+		ret[i] = v
+	}
+	return ret, nil
+}
+
+func (d *msgPackDecoder) decodeMap(n int) (interface{}, error) {
+	if n*2 > len(d.data)-d.pos {
+		return nil, errMsgPackShort
+	}
+	if err := d.enter(); err != nil {
+		return nil, err
+	}
+	defer func() { d.depth-- }()
+
+	keys := make([]interface{}, n)
+	values := make([]interface{}, n)
+	allString := true
+	for i := 0; i < n; i++ {
+		k, err := d.decode()
+		if err != nil {
+			return nil, err
+		}
+		v, err := d.decode()
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := k.(string); !ok {
+			allString = false
+		}
+		keys[i], values[i] = k, v
+	}
+
+	if allString {
+		ret := make(map[string]interface{}, n)
+		for i := range keys {
+			ret[keys[i].(string)] = values[i]
+		}
+		return ret, nil
+	}
+	ret := make(map[interface{}]interface{}, n)
+	for i := range keys {
+		if k := reflect.ValueOf(keys[i]); k.IsValid() && !k.Type().Comparable() {
+			return nil, fmt.Errorf("msgpack: unhashable map key %T", keys[i])
+		}
+		ret[keys[i]] = values[i]
+	}
+	return ret, nil
+}
+
+// decodeExt support the timestamp extension (-1) only
+func (d *msgPackDecoder) decodeExt(n int) (interface{}, error) {
+	typ, err := d.read(1)
+	if err != nil {
+		return nil, err
+	}
+	data, err := d.read(n)
+	if err != nil {
+		return nil, err
+	}
+	if int8(typ[0]) != -1 {
+		return nil, fmt.Errorf("msgpack: unsupported extension type %d", int8(typ[0]))
+	}
+	switch n {
+	case 4:
+		return time.Unix(int64(binary.BigEndian.Uint32(data)), 0), nil
+	case 8:
+		v := binary.BigEndian.Uint64(data)
+		return time.Unix(int64(v&0x3ffffffff), int64(v>>34)), nil
+	case 12:
+		nsec := binary.BigEndian.Uint32(data[:4])
+		sec := int64(binary.BigEndian.Uint64(data[4:]))
+		return time.Unix(sec, int64(nsec)), nil
+	}
+	return nil, fmt.Errorf("msgpack: invalid timestamp length %d", n)
+}
+
+// msgPackAssign store a decoded value into rv
+func msgPackAssign(rv reflect.Value, val interface{}) error {
+	if val == nil {
+		rv.Set(reflect.Zero(rv.Type()))
+		return nil
+	}
+	if rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			rv.Set(reflect.New(rv.Type().Elem()))
+		}
+		return msgPackAssign(rv.Elem(), val)
+	}
+	if rv.Kind() == reflect.Interface && rv.NumMethod() == 0 {
+		rv.Set(reflect.ValueOf(val))
+		return nil
+	}
+	if rv.Type() == timeType {
+		t, ok := val.(time.Time)
+		if !ok {
+			return msgPackTypeError(val, rv)
+		}
+		rv.Set(reflect.ValueOf(t))
+		return nil
+	}
+
+	switch rv.Kind() {
+	case reflect.Bool:
+		b, ok := val.(bool)
+		if !ok {
+			return msgPackTypeError(val, rv)
+		}
+		rv.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		var n int64
+		switch x := val.(type) {
+		case int64:
+			n = x
+		case uint64:
+			if x > math.MaxInt64 {
+				return msgPackTypeError(val, rv)
+			}
+			n = int64(x)
+		default:
+			return msgPackTypeError(val, rv)
+		}
+		if rv.OverflowInt(n) {
+			return msgPackTypeError(val, rv)
+		}
+		rv.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		var n uint64
+		switch x := val.(type) {
+		case uint64:
+			n = x
+		case int64:
+			if x < 0 {
+				return msgPackTypeError(val, rv)
+			}
+			n = uint64(x)
+		default:
+			return msgPackTypeError(val, rv)
+		}
+		if rv.OverflowUint(n) {
+			return msgPackTypeError(val, rv)
+		}
+		rv.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		switch x := val.(type) {
+		case float64:
+			rv.SetFloat(x)
+		case int64:
+			rv.SetFloat(float64(x))
+		case uint64:
+			rv.SetFloat(float64(x))
+		default:
+			return msgPackTypeError(val, rv)
+		}
+	case reflect.String:
+		switch x := val.(type) {
+		case string:
+			rv.SetString(x)
+		case []byte:
+			rv.SetString(string(x))
+		default:
+			return msgPackTypeError(val, rv)
+		}
+	case reflect.Slice:
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			switch x := val.(type) {
+			case []byte:
+				rv.SetBytes(x)
+				return nil
+			case string:
+				rv.SetBytes([]byte(x))
+				return nil
+			}
+		}
+		items, ok := val.([]interface{})
+		if !ok {
+			return msgPackTypeError(val, rv)
+		}
+		slice := reflect.MakeSlice(rv.Type(), len(items), len(items))
+		for i, item := range items {
+			if err := msgPackAssign(slice.Index(i), item); err != nil {
+				return err
+			}
+		}
+		rv.Set(slice)
+	case reflect.Array:
+		items, ok := val.([]interface{})
+		if !ok || len(items) > rv.Len() {
+			return msgPackTypeError(val, rv)
+		}
+		for i, item := range items {
+			if err := msgPackAssign(rv.Index(i), item); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		m := reflect.MakeMap(rv.Type())
+		assign := func(k, v interface{}) error {
+			key := reflect.New(rv.Type().Key()).Elem()
+			if err := msgPackAssign(key, k); err != nil {
+				return err
+			}
+			value := reflect.New(rv.Type().Elem()).Elem()
+			if err := msgPackAssign(value, v); err != nil {
+				return err
+			}
+			m.SetMapIndex(key, value)
+			return nil
+		}
+		switch x := val.(type) {
+		case map[string]interface{}:
+			for k, v := range x {
+				if err := assign(k, v); err != nil {
+					return err
+				}
+			}
+		case map[interface{}]interface{}:
+			for k, v := range x {
+				if err := assign(k, v); err != nil {
+					return err
+				}
+			}
+		default:
+			return msgPackTypeError(val, rv)
+		}
+		rv.Set(m)
+	case reflect.Struct:
+		m, ok := val.(map[string]interface{})
+		if !ok {
+			return msgPackTypeError(val, rv)
+		}
+		for _, f := range msgPackFields(rv.Type()) {
+			v, ok := m[f.name]
+			if !ok {
+				continue
+			}
+			if err := msgPackAssign(rv.FieldByIndex(f.index), v); err != nil {
+				return fmt.Errorf("%s: %w", f.name, err)
+			}
+		}
+	default:
+		return msgPackTypeError(val, rv)
+	}
+	return nil
+}
+
+func msgPackTypeError(val interface{}, rv reflect.Value) error {
+	return fmt.Errorf("msgpack: cannot decode %T into %s", val, rv.Type())
+}
@@ -0,0 +1,112 @@
+package core
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type msgPackItem struct {
+	Name  string            `msgpack:"name"`
+	Count int               `json:"count"`
+	Tags  []string          `msgpack:"tags"`
+	Attrs map[string]string `msgpack:"attrs"`
+	At    time.Time         `msgpack:"at"`
+	Ptr   *float64          `msgpack:"ptr"`
+}
+
+func TestMsgPackRoundTrip(t *testing.T) {
+	f := 1.5
+	in := msgPackItem{
+		Name:  "a",
+		Count: -300,
+		Tags:  []string{"x", "y"},
+		Attrs: map[string]string{"k": "v"},
+		At:    time.Unix(1700000000, 123).UTC(),
+		Ptr:   &f,
+	}
+	b, err := MsgPackCodec.Marshal(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out msgPackItem
+	if err := MsgPackCodec.Unmarshal(b, &out); err != nil {
+		t.Fatal(err)
+	}
+	out.At = out.At.UTC()
+	if !reflect.DeepEqual(in, out) {
+		t.Fatalf("got %+v, want %+v", out, in)
+	}
+}
+
+func TestMsgPackDeepNesting(t *testing.T) {
+	var v interface{}
+
+	// 8MB 的 0x91，即 fixarray(1) 一层层嵌套
+	data := bytes.Repeat([]byte{0x91}, 8<<20)
+	if err := MsgPackCodec.Unmarshal(data, &v); !errors.Is(err, errMsgPackTooDeep) {
+		t.Fatalf("array nesting: got %v, want %v", err, errMsgPackTooDeep)
+	}
+
+	// fixmap(1) 的值不断嵌套
+	data = bytes.Repeat([]byte{0x81, 0xa1, 'k'}, msgPackMaxDepth+1)
+	if err := MsgPackCodec.Unmarshal(data, &v); !errors.Is(err, errMsgPackTooDeep) {
+		t.Fatalf("map nesting: got %v, want %v", err, errMsgPackTooDeep)
+	}
+
+	// 深度恰好在限制内时可以解码
+	data = append(bytes.Repeat([]byte{0x91}, msgPackMaxDepth-1), 0x90)
+	if err := MsgPackCodec.Unmarshal(data, &v); err != nil {
+		t.Fatalf("nesting at limit: %v", err)
+	}
+}
+
+func TestMsgPackInvalidInput(t *testing.T) {
+	cases := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"truncated str8", []byte{0xd9, 0x05, 'a', 'b'}},
+		{"truncated uint32", []byte{0xce, 0x00, 0x01}},
+		{"truncated float64", []byte{0xcb, 0x00}},
+		{"truncated array", []byte{0x93, 0x01, 0x02}},
+		{"truncated map", []byte{0x82, 0xa1, 'a', 0x01}},
+		{"forged array32 length", []byte{0xdd, 0xff, 0xff, 0xff, 0xff, 0x01}},
+		{"forged map32 length", []byte{0xdf, 0xff, 0xff, 0xff, 0xff, 0xa1, 'a'}},
+		{"forged bin32 length", []byte{0xc6, 0xff, 0xff, 0xff, 0xff, 0x00}},
+		{"forged str32 length", []byte{0xdb, 0x7f, 0xff, 0xff, 0xff, 'a'}},
+		{"forged ext32 length", []byte{0xc9, 0xff, 0xff, 0xff, 0xff, 0xff}},
+		{"invalid code", []byte{0xc1}},
+		{"unsupported ext", []byte{0xd4, 0x05, 0x00}},
+		{"trailing data", []byte{0x01, 0x02}},
+	}
+	for _, c := range cases {
+		var v interface{}
+		if err := MsgPackCodec.Unmarshal(c.data, &v); err == nil {
+			t.Errorf("%s: expected error, got %v", c.name, v)
+		}
+	}
+}
+
+func TestMsgPackBindDeepNesting(t *testing.T) {
+	c := New()
+	c.Post("/bind", func(ctx *Context) error {
+		var v map[string]interface{}
+		ctx.MustBind(&v)
+		return nil
+	})
+
+	body := bytes.Repeat([]byte{0x91}, 1<<20)
+	req := httptest.NewRequest(http.MethodPost, "/bind", bytes.NewReader(body))
+	req.Header.Set("Content-Type", MIMEMsgPack)
+	w := httptest.NewRecorder()
+	c.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("got status %d, want %d", w.Code, http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,168 @@
+package core
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	MIMEHTML  = "text/html"
+	MIMEPlain = "text/plain"
+)
+
+// RenderFunc write data as one content type, used by Negotiate
+type RenderFunc func(ctx *Context, data interface{}) IResponse
+
+// HTMLData is the data Negotiate expects for text/html, the template rendered with Data
+type HTMLData struct {
+	Template string
+	Data     interface{}
+}
+
+var (
+	renderers     = map[string]RenderFunc{}
+	renderersLock sync.RWMutex
+
+	defaultOffers = []string{MIMEJSON, MIMEXML, MIMEPlain}
+)
+
+func init() {
+	RegisterRenderer(MIMEJSON, func(ctx *Context, data interface{}) IResponse { return ctx.JSON(data) })
+	RegisterRenderer(MIMEXML, func(ctx *Context, data interface{}) IResponse { return ctx.XML(data) })
+	RegisterRenderer(MIMEXML2, func(ctx *Context, data interface{}) IResponse { return ctx.XML(data) })
+	RegisterRenderer(MIMEPlain, func(ctx *Context, data interface{}) IResponse { return ctx.Text("%v", data) })
+	RegisterRenderer(MIMEHTML, func(ctx *Context, data interface{}) IResponse {
+		if h, ok := data.(HTMLData); ok {
+			return ctx.HTML(h.Template, h.Data)
+		}
+		// 与 HTML 渲染失败一样交给错误处理
+		ctx.Error(fmt.Errorf("negotiate %s: data must be HTMLData, got %T", MIMEHTML, data))
+		return ctx
+	})
+}
+
+// RegisterRenderer make fn the renderer of the content type for Negotiate, content types without a
+// renderer fall back to the codec registered for them
+func RegisterRenderer(contentType string, fn RenderFunc) {
+	renderersLock.Lock()
+	defer renderersLock.Unlock()
+	renderers[strings.ToLower(contentType)] = fn
+}
+
+func lookupRenderer(contentType string) (RenderFunc, bool) {
+	renderersLock.RLock()
+	fn, ok := renderers[contentType]
+	renderersLock.RUnlock()
+	if ok {
+		return fn, true
+	}
+	if c, ok := LookupCodec(contentType); ok {
+		return func(ctx *Context, data interface{}) IResponse { return ctx.Render(c, data) }, true
+	}
+	return nil, false
+}
+
+// Negotiate render data as the offer the Accept header prefers, offers default to JSON, XML and
+// plain text, it responds 406 when no offer is acceptable
+func (ctx *Context) Negotiate(offers []string, data interface{}) IResponse {
+	if len(offers) == 0 {
+		offers = defaultOffers
+	}
+	ctx.SetHeader("Vary", "Accept")
+
+	format := ctx.NegotiateFormat(offers...)
+	if format == "" {
+		return ctx.SetStatus(http.StatusNotAcceptable).Text("not acceptable, available: %s", strings.Join(offers, ", "))
+	}
+	fn, ok := lookupRenderer(format)
+	if !ok {
+		return ctx.SetStatus(http.StatusInternalServerError).Text("no renderer for %s", format)
+	}
+	return fn(ctx, data)
+}
+
+// NegotiateFormat return the offer with the highest q-value in the Accept header, the earlier offer
+// wins a tie, "" means none is acceptable; without an Accept header the first offer is returned
+func (ctx *Context) NegotiateFormat(offers ...string) string {
+	if len(offers) == 0 {
+		return ""
+	}
+	accept := ""
+	if ctx.request != nil {
+		accept = strings.Join(ctx.request.Header.Values("Accept"), ",")
+	}
+	if strings.TrimSpace(accept) == "" {
+		return offers[0]
+	}
+
+	ranges := parseAccept(accept)
+	best, bestQ := "", 0.0
+	for _, offer := range offers {
+		mediaType, _, err := mime.ParseMediaType(offer)
+		if err != nil {
+			continue
+		}
+		if q := matchAccept(ranges, mediaType); q > bestQ {
+			best, bestQ = mediaType, q
+		}
+	}
+	return best
+}
+
+type acceptRange struct {
+	typ, subtype string
+	q            float64
+}
+
+func parseAccept(accept string) []acceptRange {
+	var ranges []acceptRange
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if f, err := strconv.ParseFloat(v, 64); err == nil && f >= 0 && f <= 1 {
+				q = f
+			} else {
+				continue
+			}
+		}
+		typ, subtype := mediaType, "*"
+		if i := strings.Index(mediaType, "/"); i >= 0 {
+			typ, subtype = mediaType[:i], mediaType[i+1:]
+		}
+		ranges = append(ranges, acceptRange{typ: typ, subtype: subtype, q: q})
+	}
+	return ranges
+}
+
+// matchAccept return the q-value of the most specific range matching mediaType
+func matchAccept(ranges []acceptRange, mediaType string) float64 {
+	typ, subtype := mediaType, ""
+	if i := strings.Index(mediaType, "/"); i >= 0 {
+		typ, subtype = mediaType[:i], mediaType[i+1:]
+	}
+
+	q, specificity := 0.0, -1
+	for _, r := range ranges {
+		s := -1
+		switch {
+		case r.typ == typ && r.subtype == subtype:
+			s = 2
+		case r.typ == typ && r.subtype == "*":
+			s = 1
+		case r.typ == "*" && r.subtype == "*":
+			s = 0
+		}
+		if s > specificity {
+			q, specificity = r.q, s
+		}
+	}
+	return q
+}
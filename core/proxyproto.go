@@ -0,0 +1,213 @@
+package core
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrProxyHeaderInvalid = errors.New("invalid PROXY protocol header")
+
+	proxyV2Signature = []byte("\r\n\r\n\x00\r\nQUIT\n")
+)
+
+const (
+	proxyV1MaxLength          = 107
+	defaultProxyHeaderTimeout = 5 * time.Second
+)
+
+// ProxyProtoListener parse HAProxy PROXY protocol v1 and v2 headers sent by trusted load balancers,
+// so RemoteAddr of the accepted conn is the real client address
+type ProxyProtoListener struct {
+	net.Listener
+	trusted []*net.IPNet
+	// HeaderTimeout bound the time to read the header, default 5s
+	HeaderTimeout time.Duration
+}
+
+// NewProxyProtoListener wrap l, only peers in the trusted CIDRs may send a PROXY header,
+// connections from other peers are passed through untouched
+func NewProxyProtoListener(l net.Listener, trusted []string) (*ProxyProtoListener, error) {
+	nets, err := parseCIDRs(trusted)
+	if err != nil {
+		return nil, err
+	}
+	return &ProxyProtoListener{
+		Listener:      l,
+		trusted:       nets,
+		HeaderTimeout: defaultProxyHeaderTimeout,
+	}, nil
+}
+
+func (l *ProxyProtoListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	ip := parseHostIP(conn.RemoteAddr().String())
+	if ip == nil || !containsIP(l.trusted, ip) {
+		return conn, nil
+	}
+	return &proxyConn{
+		Conn:    conn,
+		reader:  bufio.NewReader(conn),
+		timeout: l.HeaderTimeout,
+	}, nil
+}
+
+// proxyConn read the PROXY header lazily, on the first Read or RemoteAddr, so Accept never blocks
+type proxyConn struct {
+	net.Conn
+	reader  *bufio.Reader
+	timeout time.Duration
+
+	once       sync.Once
+	err        error
+	remoteAddr net.Addr
+	localAddr  net.Addr
+}
+
+func (c *proxyConn) Read(b []byte) (int, error) {
+	c.once.Do(c.readHeader)
+	if c.err != nil {
+		return 0, c.err
+	}
+	return c.reader.Read(b)
+}
+
+func (c *proxyConn) RemoteAddr() net.Addr {
+	c.once.Do(c.readHeader)
+	if c.remoteAddr != nil {
+		return c.remoteAddr
+	}
+	return c.Conn.RemoteAddr()
+}
+
+func (c *proxyConn) LocalAddr() net.Addr {
+	c.once.Do(c.readHeader)
+	if c.localAddr != nil {
+		return c.localAddr
+	}
+	return c.Conn.LocalAddr()
+}
+
+func (c *proxyConn) readHeader() {
+	if c.timeout > 0 {
+		c.Conn.SetReadDeadline(time.Now().Add(c.timeout))
+		defer c.Conn.SetReadDeadline(time.Time{})
+	}
+
+	first, err := c.reader.Peek(1)
+	if err != nil {
+		if err != io.EOF {
+			c.err = err
+		}
+		return
+	}
+	switch first[0] {
+	case 'P':
+		c.err = c.readV1()
+	case '\r':
+		c.err = c.readV2()
+	}
+	if c.err != nil {
+		c.Conn.Close()
+	}
+}
+
+// readV1 parse "PROXY TCP4 src dst sport dport\r\n"
+func (c *proxyConn) readV1() error {
+	prefix, err := c.reader.Peek(6)
+	if err != nil || string(prefix) != "PROXY " {
+		// not a header, let the application see the bytes
+		return nil
+	}
+
+	var line []byte
+	for len(line) < proxyV1MaxLength {
+		b, err := c.reader.ReadByte()
+		if err != nil {
+			return err
+		}
+		line = append(line, b)
+		if b == '\n' {
+			break
+		}
+	}
+	if !bytes.HasSuffix(line, []byte("\r\n")) {
+		return fmt.Errorf("%w: v1 line too long", ErrProxyHeaderInvalid)
+	}
+
+	fields := strings.Fields(string(line[:len(line)-2]))
+	if len(fields) >= 2 && fields[1] == "UNKNOWN" {
+		return nil
+	}
+	if len(fields) != 6 || (fields[1] != "TCP4" && fields[1] != "TCP6") {
+		return fmt.Errorf("%w: %q", ErrProxyHeaderInvalid, line)
+	}
+	src, dst := net.ParseIP(fields[2]), net.ParseIP(fields[3])
+	sport, err1 := strconv.ParseUint(fields[4], 10, 16)
+	dport, err2 := strconv.ParseUint(fields[5], 10, 16)
+	if src == nil || dst == nil || err1 != nil || err2 != nil {
+		return fmt.Errorf("%w: %q", ErrProxyHeaderInvalid, line)
+	}
+	c.remoteAddr = &net.TCPAddr{IP: src, Port: int(sport)}
+	c.localAddr = &net.TCPAddr{IP: dst, Port: int(dport)}
+	return nil
+}
+
+// readV2 parse the binary header: signature, version/command, family, length, addresses
+func (c *proxyConn) readV2() error {
+	sig, err := c.reader.Peek(len(proxyV2Signature))
+	if err != nil || !bytes.Equal(sig, proxyV2Signature) {
+		return nil
+	}
+
+	header := make([]byte, 16)
+	if _, err := io.ReadFull(c.reader, header); err != nil {
+		return err
+	}
+	verCmd, family := header[12], header[13]
+	length := binary.BigEndian.Uint16(header[14:16])
+	if verCmd>>4 != 2 {
+		return fmt.Errorf("%w: version %d", ErrProxyHeaderInvalid, verCmd>>4)
+	}
+
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(c.reader, payload); err != nil {
+		return err
+	}
+
+	// LOCAL command: health check from the proxy itself, keep the real peer address
+	if verCmd&0x0f == 0 {
+		return nil
+	}
+	if verCmd&0x0f != 1 {
+		return fmt.Errorf("%w: command %d", ErrProxyHeaderInvalid, verCmd&0x0f)
+	}
+
+	switch family >> 4 {
+	case 1: // AF_INET
+		if len(payload) < 12 {
+			return fmt.Errorf("%w: short ipv4 addresses", ErrProxyHeaderInvalid)
+		}
+		c.remoteAddr = &net.TCPAddr{IP: net.IP(payload[0:4]), Port: int(binary.BigEndian.Uint16(payload[8:10]))}
+		c.localAddr = &net.TCPAddr{IP: net.IP(payload[4:8]), Port: int(binary.BigEndian.Uint16(payload[10:12]))}
+	case 2: // AF_INET6
+		if len(payload) < 36 {
+			return fmt.Errorf("%w: short ipv6 addresses", ErrProxyHeaderInvalid)
+		}
+		c.remoteAddr = &net.TCPAddr{IP: net.IP(payload[0:16]), Port: int(binary.BigEndian.Uint16(payload[32:34]))}
+		c.localAddr = &net.TCPAddr{IP: net.IP(payload[16:32]), Port: int(binary.BigEndian.Uint16(payload[34:36]))}
+	}
+	return nil
+}
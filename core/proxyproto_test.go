@@ -0,0 +1,204 @@
+package core
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+// pipeListener hand out one end of a net.Pipe whose RemoteAddr is peer
+type pipeListener struct {
+	conns chan net.Conn
+}
+
+func (l *pipeListener) Accept() (net.Conn, error) {
+	c, ok := <-l.conns
+	if !ok {
+		return nil, io.EOF
+	}
+	return c, nil
+}
+
+func (l *pipeListener) Close() error   { return nil }
+func (l *pipeListener) Addr() net.Addr { return &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 80} }
+
+type peerConn struct {
+	net.Conn
+	peer net.Addr
+}
+
+func (c *peerConn) RemoteAddr() net.Addr { return c.peer }
+
+func proxyV2Header(cmd, family byte, payload []byte) []byte {
+	b := append([]byte(nil), proxyV2Signature...)
+	b = append(b, 0x20|cmd, family)
+	b = binary.BigEndian.AppendUint16(b, uint16(len(payload)))
+	return append(b, payload...)
+}
+
+func proxyV4Payload(src, dst string, sport, dport uint16) []byte {
+	b := append([]byte(nil), net.ParseIP(src).To4()...)
+	b = append(b, net.ParseIP(dst).To4()...)
+	b = binary.BigEndian.AppendUint16(b, sport)
+	return binary.BigEndian.AppendUint16(b, dport)
+}
+
+func proxyV6Payload(src, dst string, sport, dport uint16) []byte {
+	b := append([]byte(nil), net.ParseIP(src).To16()...)
+	b = append(b, net.ParseIP(dst).To16()...)
+	b = binary.BigEndian.AppendUint16(b, sport)
+	return binary.BigEndian.AppendUint16(b, dport)
+}
+
+func TestProxyProtoListener(t *testing.T) {
+	tlvs := []byte{0x01, 0x00, 0x02, 'h', '2', 0x04, 0x00, 0x01, 0x00}
+
+	cases := []struct {
+		name    string
+		peer    string
+		header  []byte
+		remote  string
+		invalid bool
+		// 不可信的连接原样收到头部
+		raw bool
+	}{
+		{
+			name:   "v1 tcp4",
+			peer:   "10.0.0.1:40000",
+			header: []byte("PROXY TCP4 192.0.2.10 10.0.0.2 51234 443\r\n"),
+			remote: "192.0.2.10:51234",
+		},
+		{
+			name:   "v1 tcp6",
+			peer:   "10.0.0.1:40000",
+			header: []byte("PROXY TCP6 2001:db8::1 2001:db8::2 51234 443\r\n"),
+			remote: "[2001:db8::1]:51234",
+		},
+		{
+			name:   "v1 unknown keeps the peer",
+			peer:   "10.0.0.1:40000",
+			header: []byte("PROXY UNKNOWN\r\n"),
+			remote: "10.0.0.1:40000",
+		},
+		{
+			name:    "v1 bad address",
+			peer:    "10.0.0.1:40000",
+			header:  []byte("PROXY TCP4 not-an-ip 10.0.0.2 51234 443\r\n"),
+			invalid: true,
+		},
+		{
+			name:    "v1 truncated",
+			peer:    "10.0.0.1:40000",
+			header:  []byte("PROXY TCP4 192.0.2.10 10.0"),
+			invalid: true,
+		},
+		{
+			name:   "v2 proxy ipv4",
+			peer:   "10.0.0.1:40000",
+			header: proxyV2Header(0x1, 0x11, proxyV4Payload("192.0.2.10", "10.0.0.2", 51234, 443)),
+			remote: "192.0.2.10:51234",
+		},
+		{
+			name:   "v2 proxy ipv6",
+			peer:   "10.0.0.1:40000",
+			header: proxyV2Header(0x1, 0x21, proxyV6Payload("2001:db8::1", "2001:db8::2", 51234, 443)),
+			remote: "[2001:db8::1]:51234",
+		},
+		{
+			name:   "v2 proxy with tlvs",
+			peer:   "10.0.0.1:40000",
+			header: proxyV2Header(0x1, 0x11, append(proxyV4Payload("192.0.2.10", "10.0.0.2", 51234, 443), tlvs...)),
+			remote: "192.0.2.10:51234",
+		},
+		{
+			name:   "v2 local keeps the peer",
+			peer:   "10.0.0.1:40000",
+			header: proxyV2Header(0x0, 0x00, nil),
+			remote: "10.0.0.1:40000",
+		},
+		{
+			name:    "v2 short addresses",
+			peer:    "10.0.0.1:40000",
+			header:  proxyV2Header(0x1, 0x11, []byte{192, 0, 2, 10}),
+			invalid: true,
+		},
+		{
+			name:    "v2 truncated payload",
+			peer:    "10.0.0.1:40000",
+			header:  proxyV2Header(0x1, 0x11, proxyV4Payload("192.0.2.10", "10.0.0.2", 51234, 443))[:20],
+			invalid: true,
+		},
+		{
+			name:    "v2 bad command",
+			peer:    "10.0.0.1:40000",
+			header:  proxyV2Header(0x7, 0x11, proxyV4Payload("192.0.2.10", "10.0.0.2", 51234, 443)),
+			invalid: true,
+		},
+		{
+			name:   "untrusted peer is not parsed",
+			peer:   "203.0.113.5:40000",
+			header: []byte("PROXY TCP4 192.0.2.10 10.0.0.2 51234 443\r\n"),
+			remote: "203.0.113.5:40000",
+			raw:    true,
+		},
+	}
+
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			server, client := net.Pipe()
+			defer client.Close()
+
+			peer, _ := net.ResolveTCPAddr("tcp", c.peer)
+			conns := make(chan net.Conn, 1)
+			conns <- &peerConn{Conn: server, peer: peer}
+			close(conns)
+
+			l, err := NewProxyProtoListener(&pipeListener{conns: conns}, []string{"10.0.0.0/8"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			l.HeaderTimeout = time.Second
+			conn, err := l.Accept()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer conn.Close()
+
+			go func() {
+				client.Write(c.header)
+				if c.invalid {
+					client.Close()
+					return
+				}
+				client.Write([]byte("GET"))
+			}()
+
+			buf := make([]byte, 3)
+			_, err = io.ReadFull(conn, buf)
+			if c.invalid {
+				if err == nil {
+					t.Fatalf("expected error, read %q", buf)
+				}
+				if !errors.Is(err, ErrProxyHeaderInvalid) && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+					t.Fatalf("unexpected error %v", err)
+				}
+				return
+			}
+			if c.raw {
+				if string(buf) != "PRO" {
+					t.Fatalf("got %q, want the raw header", buf)
+				}
+				client.Close()
+			} else if err != nil || string(buf) != "GET" {
+				t.Fatalf("read %q, %v", buf, err)
+			}
+			if got := conn.RemoteAddr().String(); got != c.remote {
+				t.Fatalf("got remote %s, want %s", got, c.remote)
+			}
+		})
+	}
+}
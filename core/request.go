@@ -1,13 +1,10 @@
 package core
 
 import (
-	"bytes"
-	"encoding/json"
-	"encoding/xml"
 	"errors"
-	"io/ioutil"
 	"mime/multipart"
-	"net"
+	"net/http"
+	"net/url"
 	"strings"
 
 	"github.com/spf13/cast"
@@ -25,6 +22,8 @@ type IRequest interface {
 	QueryString(key string, def string) (string, bool)
 	QueryStringSlice(key string, def []string) ([]string, bool)
 	Query(key string) interface{}
+	QueryAll() map[string][]string
+	QueryMap(key string) map[string]string
 
 	// ParamXXX get router params, such as /user/:id
 	ParamInt(key string, def int) (int, bool)
@@ -44,17 +43,82 @@ type IRequest interface {
 	FormString(key string, def string) (string, bool)
 	FormStringSlice(key string, def []string) ([]string, bool)
 	FormFile(key string) (multipart.File, *multipart.FileHeader, error)
+	FormFiles(key string) ([]*multipart.FileHeader, error)
+	MultipartForm() (*multipart.Form, error)
+	SaveUploadedFile(file *multipart.FileHeader, dst string) error
 	Form(key string) interface{}
+	FormAll() map[string][]string
+	PostFormAll() map[string][]string
+	MergedFormAll() map[string][]string
+	PostFormAllE() (map[string][]string, error)
+	MergedFormAllE() (map[string][]string, error)
+
+	// QueryXXXE report missing keys, parse failures and range overflow as *ValueError
+	QueryIntE(key string) (int, error)
+	QueryInt32E(key string) (int32, error)
+	QueryInt64E(key string) (int64, error)
+	QueryUintE(key string) (uint, error)
+	QueryUint32E(key string) (uint32, error)
+	QueryUint64E(key string) (uint64, error)
+	QueryFloat32E(key string) (float32, error)
+	QueryFloat64E(key string) (float64, error)
+	QueryBoolE(key string) (bool, error)
+	QueryStringE(key string) (string, error)
+
+	// ParamXXXE report missing keys, parse failures and range overflow as *ValueError
+	ParamIntE(key string) (int, error)
+	ParamInt32E(key string) (int32, error)
+	ParamInt64E(key string) (int64, error)
+	ParamUintE(key string) (uint, error)
+	ParamUint32E(key string) (uint32, error)
+	ParamUint64E(key string) (uint64, error)
+	ParamFloat32E(key string) (float32, error)
+	ParamFloat64E(key string) (float64, error)
+	ParamBoolE(key string) (bool, error)
+	ParamStringE(key string) (string, error)
+
+	// FormXXXE report missing keys, parse failures and range overflow as *ValueError
+	FormIntE(key string) (int, error)
+	FormInt32E(key string) (int32, error)
+	FormInt64E(key string) (int64, error)
+	FormUintE(key string) (uint, error)
+	FormUint32E(key string) (uint32, error)
+	FormUint64E(key string) (uint64, error)
+	FormFloat32E(key string) (float32, error)
+	FormFloat64E(key string) (float64, error)
+	FormBoolE(key string) (bool, error)
+	FormStringE(key string) (string, error)
+
+	// Bind decode the request body by Content-Type, MustBind also respond 400 on error
+	Bind(obj interface{}) error
+	ShouldBind(obj interface{}) error
+	MustBind(obj interface{}) error
+
+	// BindXXX set struct fields from tags such as `query:"page"`, `path:"id"`, `header:"X-Tenant"`, `form:"name"`
+	BindQuery(obj interface{}) error
+	BindPath(obj interface{}) error
+	BindHeader(obj interface{}) error
+	BindForm(obj interface{}) error
+	BindRequest(obj interface{}) error
+
+	// Validate check obj against its `validate` tags, binders call it after decoding
+	Validate(obj interface{}) error
 
 	BindJson(obj interface{}) error
 
 	BindXml(obj interface{}) error
 
+	BindYaml(obj interface{}) error
+	BindMsgPack(obj interface{}) error
+	BindProtoBuf(obj interface{}) error
+	BindCsv(obj interface{}) error
+
 	GetRawData() ([]byte, error)
 
 	Uri() string
 	Method() string
 	Host() string
+	ClientIP() string
 	ClintIP() string
 
 	Headers() map[string][]string
@@ -64,15 +128,45 @@ type IRequest interface {
 	Cookie(key string) (string, bool)
 }
 
+// QueryAll return a copy of the query values, changing it does not affect the other getters
 func (ctx *Context) QueryAll() map[string][]string {
+	return copyValues(ctx.query())
+}
+
+// query parse the query string once and cache it for the lifetime of the request
+func (ctx *Context) query() url.Values {
+	if ctx.queryCache != nil {
+		return ctx.queryCache
+	}
 	if ctx.request != nil {
-		return ctx.request.URL.Query()
+		ctx.queryCache = ctx.request.URL.Query()
+	} else {
+		ctx.queryCache = url.Values{}
 	}
-	return map[string][]string{}
+	return ctx.queryCache
+}
+
+// QueryMap collect the keys such as filter[name]=x&filter[age]=18 into {"name": "x", "age": "18"}
+func (ctx *Context) QueryMap(key string) map[string]string {
+	return collectMap(ctx.query(), key)
+}
+
+func collectMap(values map[string][]string, key string) map[string]string {
+	ret := make(map[string]string)
+	prefix := key + "["
+	for k, v := range values {
+		if !strings.HasPrefix(k, prefix) || !strings.HasSuffix(k, "]") || len(v) == 0 {
+			continue
+		}
+		if name := k[len(prefix) : len(k)-1]; name != "" {
+			ret[name] = v[0]
+		}
+	}
+	return ret
 }
 
 func (ctx *Context) QueryInt(key string, def int) (int, bool) {
-	params := ctx.QueryAll()
+	params := ctx.query()
 	if v, ok := params[key]; ok {
 		if len(v) > 0 {
 			return cast.ToInt(v[0]), true
@@ -82,7 +176,7 @@ func (ctx *Context) QueryInt(key string, def int) (int, bool) {
 }
 
 func (ctx *Context) QueryInt64(key string, def int64) (int64, bool) {
-	params := ctx.QueryAll()
+	params := ctx.query()
 	if v, ok := params[key]; ok {
 		if len(v) > 0 {
 			return cast.ToInt64(v[0]), true
@@ -92,7 +186,7 @@ func (ctx *Context) QueryInt64(key string, def int64) (int64, bool) {
 }
 
 func (ctx *Context) QueryFloat32(key string, def float32) (float32, bool) {
-	params := ctx.QueryAll()
+	params := ctx.query()
 	if v, ok := params[key]; ok {
 		if len(v) > 0 {
 			return cast.ToFloat32(v[0]), true
@@ -102,7 +196,7 @@ func (ctx *Context) QueryFloat32(key string, def float32) (float32, bool) {
 }
 
 func (ctx *Context) QueryFloat64(key string, def float64) (float64, bool) {
-	params := ctx.QueryAll()
+	params := ctx.query()
 	if v, ok := params[key]; ok {
 		if len(v) > 0 {
 			return cast.ToFloat64(v[0]), true
@@ -112,7 +206,7 @@ func (ctx *Context) QueryFloat64(key string, def float64) (float64, bool) {
 }
 
 func (ctx *Context) QueryBool(key string, def bool) (bool, bool) {
-	params := ctx.QueryAll()
+	params := ctx.query()
 	if v, ok := params[key]; ok {
 		if len(v) > 0 {
 			return cast.ToBool(v[0]), true
@@ -122,7 +216,7 @@ func (ctx *Context) QueryBool(key string, def bool) (bool, bool) {
 }
 
 func (ctx *Context) QueryString(key string, def string) (string, bool) {
-	params := ctx.QueryAll()
+	params := ctx.query()
 	if v, ok := params[key]; ok {
 		if len(v) > 0 {
 			return v[0], true
@@ -132,7 +226,7 @@ func (ctx *Context) QueryString(key string, def string) (string, bool) {
 }
 
 func (ctx *Context) QueryStringSlice(key string, def []string) ([]string, bool) {
-	params := ctx.QueryAll()
+	params := ctx.query()
 	if v, ok := params[key]; ok {
 		return v, true
 	}
@@ -140,7 +234,7 @@ func (ctx *Context) QueryStringSlice(key string, def []string) ([]string, bool)
 }
 
 func (ctx *Context) Query(key string) interface{} {
-	params := ctx.QueryAll()
+	params := ctx.query()
 	if v, ok := params[key]; ok {
 		return v[0]
 	}
@@ -198,16 +292,82 @@ func (ctx *Context) Param(key string) interface{} {
 	return nil
 }
 
+// FormAll get the values of the request body only, the same as PostFormAll
 func (ctx *Context) FormAll() map[string][]string {
-	if ctx.request != nil {
-		ctx.request.ParseForm()
-		return ctx.request.PostForm
+	return ctx.PostFormAll()
+}
+
+// PostFormAll return a copy of the values of the urlencoded or multipart request body, a body
+// that fails to parse gives an empty map, use PostFormAllE to tell it from an empty form
+func (ctx *Context) PostFormAll() map[string][]string {
+	values, _ := ctx.PostFormAllE()
+	return values
+}
+
+// PostFormAllE is PostFormAll reporting the parse error, such as ErrBodyTooLarge
+func (ctx *Context) PostFormAllE() (map[string][]string, error) {
+	values, err := ctx.postForm()
+	return copyValues(values), err
+}
+
+// MergedFormAll return a copy of the values of the request body followed by the query string values
+func (ctx *Context) MergedFormAll() map[string][]string {
+	values, _ := ctx.MergedFormAllE()
+	return values
+}
+
+// MergedFormAllE is MergedFormAll reporting the parse error
+func (ctx *Context) MergedFormAllE() (map[string][]string, error) {
+	if err := ctx.parseForm(); err != nil {
+		return map[string][]string{}, err
+	}
+	return copyValues(ctx.request.Form), nil
+}
+
+// postForm return the parsed body values without copying them, for the getters
+func (ctx *Context) postForm() (url.Values, error) {
+	if err := ctx.parseForm(); err != nil {
+		return url.Values{}, err
+	}
+	if ctx.request.PostForm == nil {
+		return url.Values{}, nil
+	}
+	return ctx.request.PostForm, nil
+}
+
+func copyValues(values map[string][]string) map[string][]string {
+	ret := make(map[string][]string, len(values))
+	for k, v := range values {
+		ret[k] = append([]string(nil), v...)
+	}
+	return ret
+}
+
+// parseForm parse the request body once, urlencoded or multipart by Content-Type
+func (ctx *Context) parseForm() error {
+	if ctx.formParsed {
+		return ctx.formErr
+	}
+	ctx.formParsed = true
+	if ctx.request == nil {
+		ctx.formErr = errors.New("ctx.request empty")
+		return ctx.formErr
+	}
+	// ParseMultipartForm 遇到非 multipart 请求时只返回 ErrNotMultipart，先单独解析才能拿到 urlencoded 的错误
+	err := ctx.request.ParseForm()
+	if err == nil {
+		err = ctx.request.ParseMultipartForm(ctx.maxMultipartMemory)
+	}
+	if errors.Is(err, http.ErrNotMultipart) {
+		err = nil
 	}
-	return map[string][]string{}
+	err = ctx.bodyError(err)
+	ctx.formErr = err
+	return err
 }
 
 func (ctx *Context) FormInt(key string, def int) (int, bool) {
-	params := ctx.FormAll()
+	params, _ := ctx.postForm()
 	if v, ok := params[key]; ok {
 		if len(v) > 0 {
 			return cast.ToInt(v[0]), true
@@ -217,7 +377,7 @@ func (ctx *Context) FormInt(key string, def int) (int, bool) {
 }
 
 func (ctx *Context) FormInt64(key string, def int64) (int64, bool) {
-	params := ctx.FormAll()
+	params, _ := ctx.postForm()
 	if v, ok := params[key]; ok {
 		if len(v) > 0 {
 			return cast.ToInt64(v[0]), true
@@ -227,7 +387,7 @@ func (ctx *Context) FormInt64(key string, def int64) (int64, bool) {
 }
 
 func (ctx *Context) FormFloat32(key string, def float32) (float32, bool) {
-	params := ctx.FormAll()
+	params, _ := ctx.postForm()
 	if v, ok := params[key]; ok {
 		if len(v) > 0 {
 			return cast.ToFloat32(v[0]), true
@@ -237,7 +397,7 @@ func (ctx *Context) FormFloat32(key string, def float32) (float32, bool) {
 }
 
 func (ctx *Context) FormFloat64(key string, def float64) (float64, bool) {
-	params := ctx.FormAll()
+	params, _ := ctx.postForm()
 	if v, ok := params[key]; ok {
 		if len(v) > 0 {
 			return cast.ToFloat64(v[0]), true
@@ -247,7 +407,7 @@ func (ctx *Context) FormFloat64(key string, def float64) (float64, bool) {
 }
 
 func (ctx *Context) FormBool(key string, def bool) (bool, bool) {
-	params := ctx.FormAll()
+	params, _ := ctx.postForm()
 	if v, ok := params[key]; ok {
 		if len(v) > 0 {
 			return cast.ToBool(v[0]), true
@@ -257,7 +417,7 @@ func (ctx *Context) FormBool(key string, def bool) (bool, bool) {
 }
 
 func (ctx *Context) FormString(key string, def string) (string, bool) {
-	params := ctx.FormAll()
+	params, _ := ctx.postForm()
 	if v, ok := params[key]; ok {
 		if len(v) > 0 {
 			return v[0], true
@@ -267,7 +427,7 @@ func (ctx *Context) FormString(key string, def string) (string, bool) {
 }
 
 func (ctx *Context) FormStringSlice(key string, def []string) ([]string, bool) {
-	params := ctx.FormAll()
+	params, _ := ctx.postForm()
 	if v, ok := params[key]; ok {
 		return v, true
 	}
@@ -275,11 +435,14 @@ func (ctx *Context) FormStringSlice(key string, def []string) ([]string, bool) {
 }
 
 func (ctx *Context) FormFile(key string) (multipart.File, *multipart.FileHeader, error) {
+	if err := ctx.parseForm(); err != nil {
+		return nil, nil, err
+	}
 	return ctx.request.FormFile(key)
 }
 
 func (ctx *Context) Form(key string) interface{} {
-	params := ctx.FormAll()
+	params, _ := ctx.postForm()
 	if v, ok := params[key]; ok {
 		if len(v) > 0 {
 			return v[0]
@@ -289,58 +452,15 @@ func (ctx *Context) Form(key string) interface{} {
 }
 
 func (ctx *Context) BindJson(obj interface{}) error {
-	if ctx.request == nil {
-		return errors.New("ctx.request empty")
-	}
-
-	body, err := ioutil.ReadAll(ctx.request.Body)
-	if err != nil {
-		return err
-	}
-
-	ctx.request.Body = ioutil.NopCloser(bytes.NewBuffer(body))
-
-	err = json.Unmarshal(body, obj)
-	if err != nil {
-		return err
-	}
-
-	return nil
+	return ctx.BindWith(obj, JSONCodec)
 }
 
 func (ctx *Context) BindXml(obj interface{}) error {
-	if ctx.request == nil {
-		return errors.New("ctx.request empty")
-	}
-
-	body, err := ioutil.ReadAll(ctx.request.Body)
-	if err != nil {
-		return err
-	}
-
-	ctx.request.Body = ioutil.NopCloser(bytes.NewBuffer(body))
-
-	err = xml.Unmarshal(body, obj)
-	if err != nil {
-		return err
-	}
-
-	return nil
+	return ctx.BindWith(obj, XMLCodec)
 }
 
 func (ctx *Context) GetRawData() ([]byte, error) {
-	if ctx.request == nil {
-		return nil, errors.New("ctx.request empty")
-	}
-
-	body, err := ioutil.ReadAll(ctx.request.Body)
-	if err != nil {
-		return nil, err
-	}
-
-	ctx.request.Body = ioutil.NopCloser(bytes.NewBuffer(body))
-
-	return body, nil
+	return ctx.readBody()
 }
 
 func (ctx *Context) Uri() string {
@@ -355,26 +475,9 @@ func (ctx *Context) Host() string {
 	return ctx.request.Host
 }
 
+// ClintIP is kept for compatibility, use ClientIP instead
 func (ctx *Context) ClintIP() string {
-	if ctx.request == nil {
-		return ""
-	}
-	r := ctx.request
-	xForwardedFor := r.Header.Get("X-Forwarded-For")
-	IP := strings.TrimSpace(strings.Split(xForwardedFor, ",")[0])
-	if IP != "" {
-		return IP
-	}
-
-	IP = strings.TrimSpace(r.Header.Get("X-Real-IP"))
-	if IP != "" {
-		return IP
-	}
-	IP, _, err := net.SplitHostPort(strings.TrimSpace(r.RemoteAddr))
-	if err != nil {
-		return ""
-	}
-	return IP
+	return ctx.ClientIP()
 }
 
 func (ctx *Context) Headers() map[string][]string {
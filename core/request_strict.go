@@ -0,0 +1,181 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+var ErrKeyNotFound = errors.New("key not found")
+
+// ValueError report a missing or malformed query, param or form value
+type ValueError struct {
+	Source string
+	Key    string
+	Value  string
+	Err    error
+}
+
+func (e *ValueError) Error() string {
+	if errors.Is(e.Err, ErrKeyNotFound) {
+		return fmt.Sprintf("%s %q: %v", e.Source, e.Key, e.Err)
+	}
+	return fmt.Sprintf("%s %q: invalid value %q: %v", e.Source, e.Key, e.Value, e.Err)
+}
+
+func (e *ValueError) Unwrap() error {
+	return e.Err
+}
+
+func (ctx *Context) queryValue(key string) (string, error) {
+	if v, ok := ctx.query()[key]; ok && len(v) > 0 {
+		return v[0], nil
+	}
+	return "", &ValueError{Source: "query", Key: key, Err: ErrKeyNotFound}
+}
+
+func (ctx *Context) paramValue(key string) (string, error) {
+	if v, ok := ctx.params[key]; ok {
+		return v, nil
+	}
+	return "", &ValueError{Source: "param", Key: key, Err: ErrKeyNotFound}
+}
+
+// formValue report a body that fails to parse, such as ErrBodyTooLarge, rather than a missing key
+func (ctx *Context) formValue(key string) (string, error) {
+	values, err := ctx.postForm()
+	if err != nil {
+		return "", err
+	}
+	if v, ok := values[key]; ok && len(v) > 0 {
+		return v[0], nil
+	}
+	return "", &ValueError{Source: "form", Key: key, Err: ErrKeyNotFound}
+}
+
+func valueError(source, key, value string, err error) error {
+	if ne, ok := err.(*strconv.NumError); ok {
+		err = ne.Err
+	}
+	return &ValueError{Source: source, Key: key, Value: value, Err: err}
+}
+
+func (ctx *Context) QueryIntE(key string) (int, error) {
+	return Query[int](ctx, key)
+}
+
+func (ctx *Context) QueryInt32E(key string) (int32, error) {
+	return Query[int32](ctx, key)
+}
+
+func (ctx *Context) QueryInt64E(key string) (int64, error) {
+	return Query[int64](ctx, key)
+}
+
+func (ctx *Context) QueryUintE(key string) (uint, error) {
+	return Query[uint](ctx, key)
+}
+
+func (ctx *Context) QueryUint32E(key string) (uint32, error) {
+	return Query[uint32](ctx, key)
+}
+
+func (ctx *Context) QueryUint64E(key string) (uint64, error) {
+	return Query[uint64](ctx, key)
+}
+
+func (ctx *Context) QueryFloat32E(key string) (float32, error) {
+	return Query[float32](ctx, key)
+}
+
+func (ctx *Context) QueryFloat64E(key string) (float64, error) {
+	return Query[float64](ctx, key)
+}
+
+func (ctx *Context) QueryBoolE(key string) (bool, error) {
+	return Query[bool](ctx, key)
+}
+
+func (ctx *Context) QueryStringE(key string) (string, error) {
+	return Query[string](ctx, key)
+}
+
+func (ctx *Context) ParamIntE(key string) (int, error) {
+	return Param[int](ctx, key)
+}
+
+func (ctx *Context) ParamInt32E(key string) (int32, error) {
+	return Param[int32](ctx, key)
+}
+
+func (ctx *Context) ParamInt64E(key string) (int64, error) {
+	return Param[int64](ctx, key)
+}
+
+func (ctx *Context) ParamUintE(key string) (uint, error) {
+	return Param[uint](ctx, key)
+}
+
+func (ctx *Context) ParamUint32E(key string) (uint32, error) {
+	return Param[uint32](ctx, key)
+}
+
+func (ctx *Context) ParamUint64E(key string) (uint64, error) {
+	return Param[uint64](ctx, key)
+}
+
+func (ctx *Context) ParamFloat32E(key string) (float32, error) {
+	return Param[float32](ctx, key)
+}
+
+func (ctx *Context) ParamFloat64E(key string) (float64, error) {
+	return Param[float64](ctx, key)
+}
+
+func (ctx *Context) ParamBoolE(key string) (bool, error) {
+	return Param[bool](ctx, key)
+}
+
+func (ctx *Context) ParamStringE(key string) (string, error) {
+	return Param[string](ctx, key)
+}
+
+func (ctx *Context) FormIntE(key string) (int, error) {
+	return Form[int](ctx, key)
+}
+
+func (ctx *Context) FormInt32E(key string) (int32, error) {
+	return Form[int32](ctx, key)
+}
+
+func (ctx *Context) FormInt64E(key string) (int64, error) {
+	return Form[int64](ctx, key)
+}
+
+func (ctx *Context) FormUintE(key string) (uint, error) {
+	return Form[uint](ctx, key)
+}
+
+func (ctx *Context) FormUint32E(key string) (uint32, error) {
+	return Form[uint32](ctx, key)
+}
+
+func (ctx *Context) FormUint64E(key string) (uint64, error) {
+	return Form[uint64](ctx, key)
+}
+
+func (ctx *Context) FormFloat32E(key string) (float32, error) {
+	return Form[float32](ctx, key)
+}
+
+func (ctx *Context) FormFloat64E(key string) (float64, error) {
+	return Form[float64](ctx, key)
+}
+
+func (ctx *Context) FormBoolE(key string) (bool, error) {
+	return Form[bool](ctx, key)
+}
+
+func (ctx *Context) FormStringE(key string) (string, error) {
+	return Form[string](ctx, key)
+}
@@ -0,0 +1,58 @@
+package core
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestQueryAllReturnsCopy(t *testing.T) {
+	ctx := NewContext(httptest.NewRequest(http.MethodGet, "/?page=2&tag=a&tag=b", nil), httptest.NewRecorder())
+
+	all := ctx.QueryAll()
+	all["page"][0] = "9"
+	all["tag"] = append(all["tag"], "c")
+	delete(all, "tag")
+
+	if page, _ := ctx.QueryInt("page", 0); page != 2 {
+		t.Fatalf("got page %d, want 2", page)
+	}
+	if tags, _ := ctx.QueryStringSlice("tag", nil); len(tags) != 2 {
+		t.Fatalf("got tags %v", tags)
+	}
+}
+
+func TestPostFormAllError(t *testing.T) {
+	body := "name=" + strings.Repeat("a", 100)
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	ctx := NewContext(r, httptest.NewRecorder())
+	ctx.SetMaxBodySize(10)
+
+	if values, err := ctx.PostFormAllE(); !errors.Is(err, ErrBodyTooLarge) || len(values) != 0 {
+		t.Fatalf("got %v %v, want %v", values, err, ErrBodyTooLarge)
+	}
+	if _, err := ctx.FormStringE("name"); !errors.Is(err, ErrBodyTooLarge) {
+		t.Fatalf("got %v, want %v", err, ErrBodyTooLarge)
+	}
+	var obj struct {
+		Name string `form:"name"`
+	}
+	if err := ctx.BindForm(&obj); !errors.Is(err, ErrBodyTooLarge) {
+		t.Fatalf("got %v, want %v", err, ErrBodyTooLarge)
+	}
+
+	r = httptest.NewRequest(http.MethodPost, "/", strings.NewReader("name=nevs"))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	ctx = NewContext(r, httptest.NewRecorder())
+	values, err := ctx.PostFormAllE()
+	if err != nil || values["name"][0] != "nevs" {
+		t.Fatalf("got %v %v", values, err)
+	}
+	values["name"][0] = "changed"
+	if name, _ := ctx.FormString("name", ""); name != "nevs" {
+		t.Fatalf("got %q, want the cached form untouched", name)
+	}
+}
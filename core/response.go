@@ -5,6 +5,7 @@ import (
 	"encoding/xml"
 	"fmt"
 	"html/template"
+	"io"
 	"net/http"
 )
 
@@ -17,12 +18,32 @@ type IResponse interface {
 
 	XML(obj interface{}) IResponse
 
+	YAML(obj interface{}) IResponse
+
+	MsgPack(obj interface{}) IResponse
+
+	ProtoBuf(obj interface{}) IResponse
+
+	CSV(obj interface{}) IResponse
+
+	Render(c Codec, obj interface{}) IResponse
+
+	Negotiate(offers []string, data interface{}) IResponse
+
 	HTML(tpl string, obj interface{}) IResponse
 
 	Text(format string, values ...interface{}) IResponse
 
 	Redirect(path string) IResponse
 
+	File(filepath string) IResponse
+
+	FileAttachment(filepath, name string) IResponse
+
+	Stream(step func(w io.Writer) bool) bool
+
+	DataFromReader(status int, length int64, contentType string, reader io.Reader, headers map[string]string) IResponse
+
 	SetHeader(key string, val string) IResponse
 
 	SetCookie(key string, val string, maxAge int, path, domain string, secure, httpOnly bool) IResponse
@@ -42,30 +63,40 @@ func (ctx *Context) JSON(obj interface{}) IResponse {
 
 	b, err := json.Marshal(obj)
 	if err != nil {
-		return ctx.SetStatus(http.StatusInternalServerError)
+		ctx.response.WriteHeader(http.StatusInternalServerError)
+		return ctx
 	}
 
-	ctx.SetHeader("Content-Type", "application/json")
+	ctx.setHeader("Content-Type", "application/json")
 	ctx.response.Write(b)
 	return ctx
 }
 
 func (ctx *Context) JSONP(obj interface{}) IResponse {
 	callBack, _ := ctx.QueryString("callback", "callback_function")
-	ctx.SetHeader("Content-type", "application/javascript")
 	callBack = template.JSEscapeString(callBack)
 
-	_, err := ctx.response.Write([]byte(callBack))
+	ctx.WriteMutex().Lock()
+	defer ctx.WriteMutex().Unlock()
+
+	if ctx.HasStopped() {
+		return ctx
+	}
+
+	ret, err := json.Marshal(obj)
 	if err != nil {
+		ctx.response.WriteHeader(http.StatusInternalServerError)
 		return ctx
 	}
 
-	_, err = ctx.response.Write([]byte("("))
+	ctx.setHeader("Content-type", "application/javascript")
+
+	_, err = ctx.response.Write([]byte(callBack))
 	if err != nil {
 		return ctx
 	}
 
-	ret, err := json.Marshal(obj)
+	_, err = ctx.response.Write([]byte("("))
 	if err != nil {
 		return ctx
 	}
@@ -93,44 +124,132 @@ func (ctx *Context) XML(obj interface{}) IResponse {
 
 	b, err := xml.Marshal(obj)
 	if err != nil {
-		return ctx.SetStatus(http.StatusInternalServerError)
+		ctx.response.WriteHeader(http.StatusInternalServerError)
+		return ctx
 	}
 
-	ctx.SetHeader("Content-Type", "application/xml")
+	ctx.setHeader("Content-Type", "application/xml")
 	ctx.response.Write(b)
 	return ctx
 }
 
+// Render write obj encoded by c with the content type of c
+func (ctx *Context) Render(c Codec, obj interface{}) IResponse {
+	ctx.WriteMutex().Lock()
+	defer ctx.WriteMutex().Unlock()
+
+	if ctx.HasStopped() {
+		return ctx
+	}
+
+	b, err := c.Marshal(obj)
+	if err != nil {
+		ctx.response.WriteHeader(http.StatusInternalServerError)
+		return ctx
+	}
+
+	ctx.setHeader("Content-Type", c.ContentType())
+	ctx.response.Write(b)
+	return ctx
+}
+
+func (ctx *Context) YAML(obj interface{}) IResponse {
+	return ctx.Render(YAMLCodec, obj)
+}
+
+func (ctx *Context) MsgPack(obj interface{}) IResponse {
+	return ctx.Render(MsgPackCodec, obj)
+}
+
+func (ctx *Context) ProtoBuf(obj interface{}) IResponse {
+	return ctx.Render(ProtoBufCodec, obj)
+}
+
+func (ctx *Context) CSV(obj interface{}) IResponse {
+	return ctx.Render(CSVCodec, obj)
+}
+
+// HTML render the template tpl loaded by Core.LoadHTMLDir or Core.LoadHTMLFS, or the template file
+// tpl when none is loaded; render errors are recorded with ctx.Error and nothing is written
 func (ctx *Context) HTML(tpl string, obj interface{}) IResponse {
-	t, err := template.New("").ParseFiles(tpl)
+	b, err := ctx.renderHTML(tpl, obj)
 	if err != nil {
+		ctx.Error(fmt.Errorf("render %s: %w", tpl, err))
 		return ctx
 	}
-	ctx.SetHeader("Content-Type", "text/html")
-	if err = t.Execute(ctx.response, obj); err != nil {
+
+	ctx.WriteMutex().Lock()
+	defer ctx.WriteMutex().Unlock()
+
+	if ctx.HasStopped() {
 		return ctx
 	}
+
+	ctx.setHeader("Content-Type", "text/html; charset=utf-8")
+	ctx.response.Write(b)
 	return ctx
 }
 
 func (ctx *Context) Text(format string, values ...interface{}) IResponse {
 	out := fmt.Sprintf(format, values...)
-	ctx.SetHeader("Content-Type", "text/plain")
+
+	ctx.WriteMutex().Lock()
+	defer ctx.WriteMutex().Unlock()
+
+	if ctx.HasStopped() {
+		return ctx
+	}
+
+	ctx.setHeader("Content-Type", "text/plain")
 	ctx.response.Write([]byte(out))
 	return ctx
 }
 
 func (ctx *Context) Redirect(path string) IResponse {
+	ctx.WriteMutex().Lock()
+	defer ctx.WriteMutex().Unlock()
+
+	if ctx.HasStopped() {
+		return ctx
+	}
+
 	http.Redirect(ctx.response, ctx.request, path, http.StatusFound)
 	return ctx
 }
 
 func (ctx *Context) SetHeader(key string, val string) IResponse {
-	ctx.response.Header().Add(key, val)
+	ctx.WriteMutex().Lock()
+	defer ctx.WriteMutex().Unlock()
+
+	if ctx.HasStopped() {
+		return ctx
+	}
+
+	ctx.setHeader(key, val)
 	return ctx
 }
 
+func (ctx *Context) setHeader(key string, val string) {
+	if ctx.response.Committed() {
+		debugPrint("header %s ignored, response already committed", key)
+		return
+	}
+	ctx.response.Header().Add(key, val)
+}
+
 func (ctx *Context) SetCookie(key string, val string, maxAge int, path, domain string, secure, httpOnly bool) IResponse {
+	ctx.WriteMutex().Lock()
+	defer ctx.WriteMutex().Unlock()
+
+	if ctx.HasStopped() {
+		return ctx
+	}
+
+	if ctx.response.Committed() {
+		debugPrint("cookie %s ignored, response already committed", key)
+		return ctx
+	}
+
 	if path == "" {
 		path = "/"
 	}
@@ -150,6 +269,13 @@ func (ctx *Context) SetCookie(key string, val string, maxAge int, path, domain s
 }
 
 func (ctx *Context) SetStatus(code int) IResponse {
+	ctx.WriteMutex().Lock()
+	defer ctx.WriteMutex().Unlock()
+
+	if ctx.HasStopped() {
+		return ctx
+	}
+
 	ctx.response.WriteHeader(code)
 	return ctx
 }
@@ -0,0 +1,43 @@
+package core
+
+import (
+	"net"
+	"net/http"
+)
+
+// SetProxyProtocol make Run and Serve accept PROXY protocol v1/v2 headers from the trusted CIDRs
+func (c *Core) SetProxyProtocol(trusted []string) error {
+	if _, err := parseCIDRs(trusted); err != nil {
+		return err
+	}
+	c.proxyProtocol = trusted
+	return nil
+}
+
+// Run listen on the TCP address addr and serve requests with c
+func (c *Core) Run(addr string) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return c.Serve(l)
+}
+
+// Serve serve requests accepted by l with c, l is wrapped by ProxyProtoListener when enabled
+func (c *Core) Serve(l net.Listener) error {
+	l, err := c.wrapListener(l)
+	if err != nil {
+		return err
+	}
+	s := &http.Server{
+		Handler: c,
+	}
+	return s.Serve(l)
+}
+
+func (c *Core) wrapListener(l net.Listener) (net.Listener, error) {
+	if c.proxyProtocol == nil {
+		return l, nil
+	}
+	return NewProxyProtoListener(l, c.proxyProtocol)
+}
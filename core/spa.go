@@ -0,0 +1,112 @@
+package core
+
+import (
+	"io/fs"
+	"net/http"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// 文件名末尾、扩展名之前的 hash：8 位 base64url（Vite/Rollup）或 hex，以及 16/20/32 位 hex（webpack）
+var hashedAssetRegexp = regexp.MustCompile(`[.-]([A-Za-z0-9_-]{8}|[0-9a-f]{16}|[0-9a-f]{20}|[0-9a-f]{32})\.[A-Za-z0-9]+$`)
+
+// isHashedAsset is the conservative default of SPAConfig.IsHashed, an 8 character hash must
+// contain a digit and be lowercase hex or mix upper and lower case, so names such as
+// bootstrap-datepicker.js or my-settings.js are not taken for hashes
+func isHashedAsset(name string) bool {
+	m := hashedAssetRegexp.FindStringSubmatch(path.Base(name))
+	if m == nil {
+		return false
+	}
+	hash := m[1]
+	if !strings.ContainsAny(hash, "0123456789") {
+		return false
+	}
+	if len(hash) > 8 || strings.Trim(hash, "0123456789abcdef") == "" {
+		return true
+	}
+	return strings.ToLower(hash) != hash && strings.ToUpper(hash) != hash
+}
+
+// SPAConfig control how Core.SPA serve a single page app
+type SPAConfig struct {
+	FS fs.FS
+	// Index is served for unknown paths, default index.html
+	Index string
+	// APIPrefixes never fall back to the index, such as "/api"
+	APIPrefixes []string
+	// IsHashed report whether the asset has a content hash in its name and can be cached forever,
+	// default matches names such as index-3f2a9c1b.js or index-Bx9_kQ2a.js; a false match keeps
+	// a changed file stale in browsers for a year, so set it when the build names hashes differently
+	IsHashed func(name string) bool
+}
+
+// SPA serve the assets of config.FS at prefix, unknown paths without an extension fall back to the
+// index so client side routing works; hashed assets are cached for a year, the index is never cached
+func (c *Core) SPA(prefix string, config SPAConfig) {
+	if config.Index == "" {
+		config.Index = "index.html"
+	}
+	if config.IsHashed == nil {
+		config.IsHashed = isHashedAsset
+	}
+	uri := strings.TrimSuffix(prefix, "/") + "/*filepath"
+	handler := func(ctx *Context) error {
+		name, _ := ctx.ParamString("filepath", "")
+		ctx.serveSPA(config, name)
+		return nil
+	}
+	c.Get(uri, handler)
+	c.Head(uri, handler)
+}
+
+func (ctx *Context) serveSPA(config SPAConfig, name string) {
+	for _, p := range config.APIPrefixes {
+		p = strings.TrimSuffix(p, "/")
+		if upath := ctx.request.URL.Path; upath == p || strings.HasPrefix(upath, p+"/") {
+			ctx.SetStatus(http.StatusNotFound).JSON("NOT FOUND ROUTER")
+			return
+		}
+	}
+
+	name, ok := cleanFSPath(name)
+	if !ok {
+		ctx.SetStatus(http.StatusNotFound).JSON("NOT FOUND FILE")
+		return
+	}
+
+	if name != "." && name != config.Index {
+		if f, err := config.FS.Open(name); err == nil {
+			defer f.Close()
+			if info, err := f.Stat(); err == nil && !info.IsDir() {
+				if config.IsHashed(name) {
+					ctx.SetHeader("Cache-Control", "public, max-age=31536000, immutable")
+				} else {
+					ctx.SetHeader("Cache-Control", "no-cache")
+				}
+				ctx.serveContent(f, info)
+				return
+			}
+		}
+		// a missing asset is an error, not a client side route
+		if path.Ext(name) != "" {
+			ctx.SetStatus(http.StatusNotFound).JSON("NOT FOUND FILE")
+			return
+		}
+	}
+
+	f, err := config.FS.Open(config.Index)
+	if err != nil {
+		ctx.SetStatus(http.StatusNotFound).JSON("NOT FOUND FILE")
+		return
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		ctx.SetStatus(http.StatusNotFound).JSON("NOT FOUND FILE")
+		return
+	}
+	ctx.SetHeader("Cache-Control", "no-cache")
+	ctx.serveContent(f, info)
+}
@@ -0,0 +1,147 @@
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrResponseStopped = errors.New("response stopped")
+
+// SSEvent is one server-sent event, Data is written as is when it is a string or []byte and
+// as JSON otherwise, Retry tells the client how long to wait before reconnecting
+type SSEvent struct {
+	Event string
+	ID    string
+	Retry time.Duration
+	Data  interface{}
+}
+
+// StartSSE send the event stream headers, it is called by the first SSEvent if needed
+func (ctx *Context) StartSSE() error {
+	ctx.WriteMutex().Lock()
+	defer ctx.WriteMutex().Unlock()
+	return ctx.startSSE()
+}
+
+func (ctx *Context) startSSE() error {
+	if ctx.HasStopped() {
+		return ErrResponseStopped
+	}
+	if ctx.sseStarted {
+		return nil
+	}
+	ctx.sseStarted = true
+
+	header := ctx.response.Header()
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("Connection", "keep-alive")
+	// stop nginx from buffering the stream
+	header.Set("X-Accel-Buffering", "no")
+	ctx.response.Flush()
+	return nil
+}
+
+// LastEventID return the id of the last event the reconnecting client received
+func (ctx *Context) LastEventID() string {
+	if ctx.request == nil {
+		return ""
+	}
+	return ctx.request.Header.Get("Last-Event-ID")
+}
+
+// SSEvent write ev and flush it to the client
+func (ctx *Context) SSEvent(ev SSEvent) error {
+	var buf bytes.Buffer
+	if ev.Event != "" {
+		buf.WriteString("event: " + sseField(ev.Event) + "\n")
+	}
+	if ev.ID != "" {
+		buf.WriteString("id: " + sseField(ev.ID) + "\n")
+	}
+	if ev.Retry > 0 {
+		buf.WriteString("retry: " + strconv.FormatInt(ev.Retry.Milliseconds(), 10) + "\n")
+	}
+
+	var data string
+	switch v := ev.Data.(type) {
+	case nil:
+	case string:
+		data = v
+	case []byte:
+		data = string(v)
+	default:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		data = string(b)
+	}
+	for _, line := range strings.Split(strings.ReplaceAll(data, "\r\n", "\n"), "\n") {
+		buf.WriteString("data: " + line + "\n")
+	}
+	buf.WriteString("\n")
+
+	return ctx.writeSSE(buf.Bytes())
+}
+
+// SSEComment write a comment line, clients ignore it, it keeps idle connections open
+func (ctx *Context) SSEComment(comment string) error {
+	return ctx.writeSSE([]byte(": " + sseField(comment) + "\n\n"))
+}
+
+func (ctx *Context) writeSSE(b []byte) error {
+	ctx.WriteMutex().Lock()
+	defer ctx.WriteMutex().Unlock()
+
+	if err := ctx.startSSE(); err != nil {
+		return err
+	}
+	if _, err := ctx.response.Write(b); err != nil {
+		return err
+	}
+	ctx.response.Flush()
+	return nil
+}
+
+// SSEStream send the events of ch until ch is closed or the handler context is done, with a
+// heartbeat comment after each idle period of heartbeat, 0 disables it
+func (ctx *Context) SSEStream(ch <-chan SSEvent, heartbeat time.Duration) error {
+	if err := ctx.StartSSE(); err != nil {
+		return err
+	}
+
+	var tick <-chan time.Time
+	if heartbeat > 0 {
+		ticker := time.NewTicker(heartbeat)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case ev, ok := <-ch:
+			if !ok {
+				return nil
+			}
+			if err := ctx.SSEvent(ev); err != nil {
+				return err
+			}
+		case <-tick:
+			if err := ctx.SSEComment("heartbeat"); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// sseField drop line breaks, which would end the field
+func sseField(s string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(s)
+}
@@ -0,0 +1,209 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+)
+
+// StaticConfig control how Core.StaticWithConfig serve a file system
+type StaticConfig struct {
+	FS fs.FS
+	// Index is served for directories, default index.html
+	Index string
+	// Browse list directories without an index, off by default
+	Browse bool
+}
+
+// Static serve the files under dir at prefix, such as c.Static("/assets", "./public")
+func (c *Core) Static(prefix, dir string) {
+	c.StaticFS(prefix, os.DirFS(dir))
+}
+
+// StaticFS serve fsys at prefix, use fs.Sub to serve a sub directory of an embed.FS
+func (c *Core) StaticFS(prefix string, fsys fs.FS) {
+	c.StaticWithConfig(prefix, StaticConfig{FS: fsys})
+}
+
+func (c *Core) StaticWithConfig(prefix string, config StaticConfig) {
+	if config.Index == "" {
+		config.Index = "index.html"
+	}
+	uri := strings.TrimSuffix(prefix, "/") + "/*filepath"
+	handler := func(ctx *Context) error {
+		name, _ := ctx.ParamString("filepath", "")
+		ctx.serveFS(config, name)
+		return nil
+	}
+	c.Get(uri, handler)
+	c.Head(uri, handler)
+}
+
+// cleanFSPath turn a request path into a fs.FS name, ".." can not leave the root
+func cleanFSPath(name string) (string, bool) {
+	name = strings.TrimPrefix(path.Clean("/"+name), "/")
+	if name == "" {
+		name = "."
+	}
+	return name, fs.ValidPath(name)
+}
+
+func (ctx *Context) serveFS(config StaticConfig, name string) {
+	name, ok := cleanFSPath(name)
+	if !ok {
+		ctx.SetStatus(http.StatusNotFound).JSON("NOT FOUND FILE")
+		return
+	}
+
+	f, err := config.FS.Open(name)
+	if err != nil {
+		ctx.SetStatus(http.StatusNotFound).JSON("NOT FOUND FILE")
+		return
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		ctx.SetStatus(http.StatusNotFound).JSON("NOT FOUND FILE")
+		return
+	}
+
+	if info.IsDir() {
+		// relative links of the index page need the trailing slash
+		if p := ctx.request.URL.Path; !strings.HasSuffix(p, "/") {
+			ctx.Redirect(path.Base(p) + "/")
+			return
+		}
+		index, err := config.FS.Open(path.Join(name, config.Index))
+		if err == nil {
+			defer index.Close()
+			if indexInfo, err := index.Stat(); err == nil && !indexInfo.IsDir() {
+				ctx.serveContent(index, indexInfo)
+				return
+			}
+		}
+		if config.Browse {
+			ctx.listDir(f)
+			return
+		}
+		ctx.SetStatus(http.StatusNotFound).JSON("NOT FOUND FILE")
+		return
+	}
+
+	ctx.serveContent(f, info)
+}
+
+// serveContent write f with http.ServeContent, which handles Range, If-Modified-Since,
+// If-None-Match and the Content-Type by extension or sniffing
+func (ctx *Context) serveContent(f fs.File, info fs.FileInfo) {
+	rs, ok := f.(io.ReadSeeker)
+	if !ok {
+		b, err := io.ReadAll(f)
+		if err != nil {
+			ctx.SetStatus(http.StatusInternalServerError).JSON("INNER ERROR")
+			return
+		}
+		rs = bytes.NewReader(b)
+	}
+
+	ctx.WriteMutex().Lock()
+	defer ctx.WriteMutex().Unlock()
+
+	if ctx.HasStopped() {
+		return
+	}
+	http.ServeContent(ctx.response, ctx.request, info.Name(), info.ModTime(), rs)
+}
+
+func (ctx *Context) listDir(f fs.File) {
+	dir, ok := f.(fs.ReadDirFile)
+	if !ok {
+		ctx.SetStatus(http.StatusNotFound).JSON("NOT FOUND FILE")
+		return
+	}
+	entries, err := dir.ReadDir(-1)
+	if err != nil {
+		ctx.SetStatus(http.StatusInternalServerError).JSON("INNER ERROR")
+		return
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("<!doctype html>\n<pre>\n")
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() {
+			name += "/"
+		}
+		u := url.URL{Path: name}
+		fmt.Fprintf(&buf, "<a href=\"%s\">%s</a>\n", u.String(), html.EscapeString(name))
+	}
+	buf.WriteString("</pre>\n")
+
+	ctx.SetHeader("Content-Type", "text/html; charset=utf-8")
+	ctx.WriteMutex().Lock()
+	defer ctx.WriteMutex().Unlock()
+	if !ctx.HasStopped() {
+		ctx.response.Write(buf.Bytes())
+	}
+}
+
+// File serve the local file filepath with Range and conditional request support, filepath must
+// not come from the client unchecked
+func (ctx *Context) File(filepath string) IResponse {
+	return ctx.serveFile(filepath, "")
+}
+
+// FileAttachment serve filepath as a download saved under name
+func (ctx *Context) FileAttachment(filepath, name string) IResponse {
+	return ctx.serveFile(filepath, contentDisposition("attachment", name))
+}
+
+// serveFile serve filepath, disposition is only set once the file is known to exist so a 404
+// is not turned into a download
+func (ctx *Context) serveFile(filepath, disposition string) IResponse {
+	f, err := os.Open(filepath)
+	if err != nil {
+		ctx.SetStatus(http.StatusNotFound).JSON("NOT FOUND FILE")
+		return ctx
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil || info.IsDir() {
+		ctx.SetStatus(http.StatusNotFound).JSON("NOT FOUND FILE")
+		return ctx
+	}
+
+	if disposition != "" {
+		ctx.SetHeader("Content-Disposition", disposition)
+	}
+	ctx.serveContent(f, info)
+	return ctx
+}
+
+func contentDisposition(kind, name string) string {
+	ascii := true
+	for _, r := range name {
+		if r > 127 || r < 32 {
+			ascii = false
+			break
+		}
+	}
+	quoted := strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(name)
+	if ascii {
+		return fmt.Sprintf(`%s; filename="%s"`, kind, quoted)
+	}
+	return fmt.Sprintf(`%s; filename="%s"; filename*=UTF-8''%s`, kind, strings.Map(func(r rune) rune {
+		if r > 127 || r < 32 {
+			return '_'
+		}
+		return r
+	}, quoted), strings.ReplaceAll(url.QueryEscape(name), "+", "%20"))
+}
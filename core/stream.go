@@ -0,0 +1,110 @@
+package core
+
+import (
+	"io"
+	"strconv"
+)
+
+var _ io.Writer = streamWriter{}
+
+// 流式写出时每次读取的块大小
+const streamChunkSize = 32 * 1024
+
+// streamWriter write to the response under the write mutex, and fail once the response stopped
+type streamWriter struct {
+	ctx *Context
+}
+
+func (w streamWriter) Write(b []byte) (int, error) {
+	w.ctx.WriteMutex().Lock()
+	defer w.ctx.WriteMutex().Unlock()
+
+	if w.ctx.HasStopped() {
+		return 0, ErrResponseStopped
+	}
+	return w.ctx.response.Write(b)
+}
+
+func (ctx *Context) flushStream() {
+	ctx.WriteMutex().Lock()
+	defer ctx.WriteMutex().Unlock()
+
+	if !ctx.HasStopped() {
+		ctx.response.Flush()
+	}
+}
+
+// streamGone report whether the client disconnected, the handler context is done or the
+// response was stopped by a middleware
+func (ctx *Context) streamGone() bool {
+	select {
+	case <-ctx.Done():
+		return true
+	default:
+	}
+	return ctx.HasStopped()
+}
+
+// Stream call step until it returns false, flushing what it wrote after each call; it returns
+// true when it stopped because the client went away or the response was stopped
+func (ctx *Context) Stream(step func(w io.Writer) bool) bool {
+	w := streamWriter{ctx: ctx}
+	for {
+		if ctx.streamGone() {
+			return true
+		}
+		keepOpen := step(w)
+		ctx.flushStream()
+		if !keepOpen {
+			return ctx.streamGone()
+		}
+	}
+}
+
+// DataFromReader copy reader to the response with status and contentType, flushing after each
+// chunk; length sets Content-Length when it is not negative, copy errors are recorded with
+// ctx.Error for logging only, the headers are already sent so the error handler is skipped
+func (ctx *Context) DataFromReader(status int, length int64, contentType string, reader io.Reader, headers map[string]string) IResponse {
+	ctx.WriteMutex().Lock()
+	if ctx.HasStopped() {
+		ctx.WriteMutex().Unlock()
+		return ctx
+	}
+	for k, v := range headers {
+		ctx.setHeader(k, v)
+	}
+	if contentType != "" {
+		ctx.response.Header().Set("Content-Type", contentType)
+	}
+	if length >= 0 {
+		ctx.response.Header().Set("Content-Length", strconv.FormatInt(length, 10))
+	}
+	ctx.response.WriteHeader(status)
+	ctx.response.WriteHeaderNow()
+	ctx.WriteMutex().Unlock()
+
+	w := streamWriter{ctx: ctx}
+	buf := make([]byte, streamChunkSize)
+	for {
+		if ctx.streamGone() {
+			return ctx
+		}
+		n, err := reader.Read(buf)
+		if n > 0 {
+			if _, werr := w.Write(buf[:n]); werr != nil {
+				if !ctx.streamGone() {
+					ctx.Error(werr)
+				}
+				return ctx
+			}
+			ctx.flushStream()
+		}
+		if err == io.EOF {
+			return ctx
+		}
+		if err != nil {
+			ctx.Error(err)
+			return ctx
+		}
+	}
+}
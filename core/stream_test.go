@@ -0,0 +1,84 @@
+package core
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type failingReader struct {
+	data string
+	done bool
+}
+
+func (r *failingReader) Read(p []byte) (int, error) {
+	if r.done {
+		return 0, errors.New("disk read failed")
+	}
+	r.done = true
+	return copy(p, r.data), nil
+}
+
+func TestDataFromReader(t *testing.T) {
+	c := New()
+	c.Get("/file", func(ctx *Context) error {
+		ctx.DataFromReader(http.StatusOK, 7, "text/plain", strings.NewReader("content"), map[string]string{"X-Export": "1"})
+		return nil
+	})
+
+	w := httptest.NewRecorder()
+	c.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/file", nil))
+	if w.Code != http.StatusOK || w.Body.String() != "content" {
+		t.Fatalf("got %d %q", w.Code, w.Body.String())
+	}
+	if w.Header().Get("Content-Length") != "7" || w.Header().Get("X-Export") != "1" {
+		t.Fatalf("unexpected headers %v", w.Header())
+	}
+}
+
+func TestDataFromReaderErrorAfterCommit(t *testing.T) {
+	c := New()
+	var errs []error
+	c.Get("/file", func(ctx *Context) error {
+		ctx.DataFromReader(http.StatusOK, -1, "application/octet-stream", &failingReader{data: "partial-data"}, nil)
+		errs = ctx.Errors()
+		return nil
+	})
+
+	w := httptest.NewRecorder()
+	c.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/file", nil))
+	if w.Code != http.StatusOK || w.Body.String() != "partial-data" {
+		t.Fatalf("got %d %q, want the partial body without an error appended", w.Code, w.Body.String())
+	}
+	if len(errs) != 1 {
+		t.Fatalf("got errors %v, want the read error recorded", errs)
+	}
+}
+
+func TestStreamStopsWhenResponseStopped(t *testing.T) {
+	c := New()
+	c.Get("/stream", func(ctx *Context) error {
+		n := 0
+		gone := ctx.Stream(func(w io.Writer) bool {
+			n++
+			io.WriteString(w, "x")
+			if n == 3 {
+				ctx.SetHasStopped()
+			}
+			return true
+		})
+		if !gone || n != 3 {
+			t.Errorf("got gone=%v after %d steps", gone, n)
+		}
+		return nil
+	})
+
+	w := httptest.NewRecorder()
+	c.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/stream", nil))
+	if w.Body.String() != "xxx" {
+		t.Fatalf("got %q", w.Body.String())
+	}
+}
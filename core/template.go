@@ -0,0 +1,168 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+	"sync"
+)
+
+var templateExts = []string{".html", ".tmpl", ".gohtml"}
+
+// HTMLTemplates hold the templates of a directory parsed once; files under layouts/ and partials/
+// are shared, they are parsed with every other file, so a page can {{template "layouts/base.html" .}}
+// and define the blocks the layout uses; pages are rendered by their path, such as "users/show.html"
+type HTMLTemplates struct {
+	fsys  fs.FS
+	funcs template.FuncMap
+	// 每次渲染前重新加载模板，便于开发时修改模板，默认关闭
+	Reload bool
+
+	mu        sync.RWMutex
+	templates map[string]*template.Template
+}
+
+func NewHTMLTemplates(fsys fs.FS, funcs template.FuncMap) (*HTMLTemplates, error) {
+	t := &HTMLTemplates{fsys: fsys, funcs: funcs}
+	if err := t.Load(); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// Load parse all templates again
+func (t *HTMLTemplates) Load() error {
+	var shared, pages []string
+	err := fs.WalkDir(t.fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !isTemplateFile(p) {
+			return nil
+		}
+		if strings.HasPrefix(p, "layouts/") || strings.HasPrefix(p, "partials/") {
+			shared = append(shared, p)
+		} else {
+			pages = append(pages, p)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	sharedContents := make(map[string]string, len(shared))
+	for _, p := range shared {
+		b, err := fs.ReadFile(t.fsys, p)
+		if err != nil {
+			return err
+		}
+		sharedContents[p] = string(b)
+	}
+
+	templates := make(map[string]*template.Template, len(pages))
+	for _, page := range pages {
+		b, err := fs.ReadFile(t.fsys, page)
+		if err != nil {
+			return err
+		}
+		tpl := template.New(page).Funcs(t.funcs)
+		for name, content := range sharedContents {
+			if _, err := tpl.New(name).Parse(content); err != nil {
+				return err
+			}
+		}
+		if _, err := tpl.Parse(string(b)); err != nil {
+			return err
+		}
+		templates[page] = tpl
+	}
+
+	t.mu.Lock()
+	t.templates = templates
+	t.mu.Unlock()
+	return nil
+}
+
+// Render execute the page name, templates are loaded again first when Reload is set
+func (t *HTMLTemplates) Render(w io.Writer, name string, data interface{}) error {
+	if t.Reload {
+		if err := t.Load(); err != nil {
+			return err
+		}
+	}
+
+	t.mu.RLock()
+	tpl, ok := t.templates[name]
+	t.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("template not loaded: %w", fs.ErrNotExist)
+	}
+	return tpl.Execute(w, data)
+}
+
+func isTemplateFile(p string) bool {
+	ext := path.Ext(p)
+	for _, e := range templateExts {
+		if ext == e {
+			return true
+		}
+	}
+	return false
+}
+
+// SetFuncMap set the functions available to templates loaded afterwards
+func (c *Core) SetFuncMap(funcs template.FuncMap) {
+	c.funcMap = funcs
+}
+
+// SetHTMLReload make the loaded templates, and those loaded afterwards, parse their files again
+// on every render, use it during development only
+func (c *Core) SetHTMLReload(reload bool) {
+	c.htmlReload = reload
+	if c.templates != nil {
+		c.templates.Reload = reload
+	}
+}
+
+// LoadHTMLDir load the templates under dir, see HTMLTemplates for the layout
+func (c *Core) LoadHTMLDir(dir string) error {
+	return c.LoadHTMLFS(os.DirFS(dir))
+}
+
+// LoadHTMLFS load the templates of fsys, use fs.Sub to load a sub directory of an embed.FS
+func (c *Core) LoadHTMLFS(fsys fs.FS) error {
+	t, err := NewHTMLTemplates(fsys, c.funcMap)
+	if err != nil {
+		return err
+	}
+	t.Reload = c.htmlReload
+	c.templates = t
+	return nil
+}
+
+// renderHTML execute tpl into a buffer, so nothing is written when it fails
+func (ctx *Context) renderHTML(tpl string, obj interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if ctx.templates != nil {
+		if err := ctx.templates.Render(&buf, tpl, obj); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+
+	// no registry loaded, tpl is a file path
+	t, err := template.ParseFiles(tpl)
+	if err != nil {
+		return nil, err
+	}
+	if err := t.Execute(&buf, obj); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -51,17 +51,26 @@ func (n *node) matchNode(uri string) *node {
 
 	if len(segments) == 1 {
 		for _, tn := range cnodes {
-			if tn.isLast {
+			if tn.isLast && !isCatchAllSegment(tn.segment) {
 				return tn
 			}
 		}
-		return nil
+	} else {
+		for _, tn := range cnodes {
+			if isCatchAllSegment(tn.segment) {
+				continue
+			}
+			tnMatch := tn.matchNode(segments[1])
+			if tnMatch != nil {
+				return tnMatch
+			}
+		}
 	}
 
+	// catch-all segment match the rest of the uri, after any other route
 	for _, tn := range cnodes {
-		tnMatch := tn.matchNode(segments[1])
-		if tnMatch != nil {
-			return tnMatch
+		if isCatchAllSegment(tn.segment) && tn.isLast {
+			return tn
 		}
 	}
 	return nil
@@ -80,15 +89,22 @@ func (n *node) parseParamsFromEndNode(uri string) map[string]string {
 	ret := make(map[string]string)
 	segments := strings.Split(uri, "/")
 
-	cur := n
-	for i := len(segments) - 1; i >= 0; i-- {
-		if cur.segment == "" {
+	var chain []*node
+	for cur := n; cur != nil && cur.parent != nil; cur = cur.parent {
+		chain = append([]*node{cur}, chain...)
+	}
+
+	for i, cur := range chain {
+		if i >= len(segments) {
+			break
+		}
+		if isCatchAllSegment(cur.segment) {
+			ret[cur.segment[1:]] = strings.Join(segments[i:], "/")
 			break
 		}
 		if isWildSegment(cur.segment) {
 			ret[cur.segment[1:]] = segments[i]
 		}
-		cur = cur.parent
 	}
 	return ret
 }
@@ -102,13 +118,17 @@ func NewTree() *Tree {
 func (t *Tree) AddRouter(uri string, handlers []ControllerHandler) error {
 	n := t.root
 
-	if n.matchNode(uri) != nil {
+	// a catch-all route and the more specific routes under it do not conflict
+	if m := n.matchNode(uri); m != nil && isCatchAllSegment(m.segment) == strings.Contains(uri, "/*") {
 		return errors.New("route conflict: " + uri)
 	}
 
 	segments := strings.Split(uri, "/")
 
 	for i, segment := range segments {
+		if isCatchAllSegment(segment) && i != len(segments)-1 {
+			return errors.New("catch-all segment must be the last: " + uri)
+		}
 		if !isWildSegment(segment) {
 			segment = strings.ToUpper(segment)
 		}
@@ -149,6 +169,11 @@ func (t *Tree) FindNode(uri string) *node {
 	return matchNode
 }
 
+// isWildSegment report whether segment is a param such as :id, or a catch-all such as *filepath
 func isWildSegment(segment string) bool {
-	return strings.HasPrefix(segment, ":")
+	return strings.HasPrefix(segment, ":") || isCatchAllSegment(segment)
+}
+
+func isCatchAllSegment(segment string) bool {
+	return strings.HasPrefix(segment, "*")
 }
@@ -0,0 +1,149 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	ErrFileTooLarge    = errors.New("uploaded file too large")
+	ErrFileTypeInvalid = errors.New("uploaded file type not allowed")
+)
+
+// MultipartForm parse the multipart body within the multipart memory limit
+func (ctx *Context) MultipartForm() (*multipart.Form, error) {
+	if err := ctx.parseForm(); err != nil {
+		return nil, err
+	}
+	if ctx.request.MultipartForm == nil {
+		return nil, http.ErrNotMultipart
+	}
+	return ctx.request.MultipartForm, nil
+}
+
+// FormFiles get all files uploaded with key
+func (ctx *Context) FormFiles(key string) ([]*multipart.FileHeader, error) {
+	form, err := ctx.MultipartForm()
+	if err != nil {
+		return nil, err
+	}
+	files, ok := form.File[key]
+	if !ok || len(files) == 0 {
+		return nil, http.ErrMissingFile
+	}
+	return files, nil
+}
+
+// SaveUploadedFile copy the uploaded file to dst, creating the parent directories
+func (ctx *Context) SaveUploadedFile(file *multipart.FileHeader, dst string) error {
+	src, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	if err = os.MkdirAll(filepath.Dir(dst), 0750); err != nil {
+		return err
+	}
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	if _, err = io.Copy(out, src); err != nil {
+		return err
+	}
+	return out.Close()
+}
+
+// DetectContentType sniff the content type from the first 512 bytes of the file instead of
+// trusting the type sent by the client
+func DetectContentType(file *multipart.FileHeader) (string, error) {
+	src, err := file.Open()
+	if err != nil {
+		return "", err
+	}
+	defer src.Close()
+
+	buf := make([]byte, 512)
+	n, err := io.ReadFull(src, buf)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return "", err
+	}
+	return http.DetectContentType(buf[:n]), nil
+}
+
+// CheckFile check the size and the sniffed content type of the file, an allowed type may be
+// a full type such as "image/png" or a prefix such as "image/"; it returns the sniffed type
+func CheckFile(file *multipart.FileHeader, maxSize int64, allowedTypes ...string) (string, error) {
+	if maxSize > 0 && file.Size > maxSize {
+		return "", fmt.Errorf("%w: %s is %d bytes, limit is %d bytes", ErrFileTooLarge, file.Filename, file.Size, maxSize)
+	}
+	contentType, err := DetectContentType(file)
+	if err != nil {
+		return "", err
+	}
+	if len(allowedTypes) > 0 && !matchContentType(contentType, allowedTypes) {
+		return contentType, fmt.Errorf("%w: %s is %s", ErrFileTypeInvalid, file.Filename, contentType)
+	}
+	return contentType, nil
+}
+
+func matchContentType(contentType string, allowedTypes []string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = contentType
+	}
+	for _, t := range allowedTypes {
+		if mediaType == t || (strings.HasSuffix(t, "/") && strings.HasPrefix(mediaType, t)) {
+			return true
+		}
+	}
+	return false
+}
+
+// StreamParts read the multipart body part by part without buffering the form in memory or
+// temp files, the reader given to fn fails with ErrFileTooLarge past maxPartSize bytes, 0 means no limit;
+// it can not be used together with MultipartForm, FormFile or the form getters
+func (ctx *Context) StreamParts(maxPartSize int64, fn func(part *multipart.Part, r io.Reader) error) error {
+	if ctx.request == nil {
+		return errors.New("ctx.request empty")
+	}
+	if ctx.formParsed {
+		return errors.New("multipart body already parsed")
+	}
+	reader, err := ctx.request.MultipartReader()
+	if err != nil {
+		return err
+	}
+	ctx.formParsed = true
+
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return ctx.bodyError(err)
+		}
+
+		var r io.Reader = part
+		if maxPartSize > 0 {
+			r = NewLimitReader(part, maxPartSize, fmt.Errorf("%w: %s exceeds %d bytes", ErrFileTooLarge, part.FileName(), maxPartSize))
+		}
+		err = fn(part, r)
+		part.Close()
+		if err != nil {
+			return ctx.bodyError(err)
+		}
+	}
+}
@@ -0,0 +1,462 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+const tagValidate = "validate"
+
+// ErrInvalidValidateTag is wrapped by the error returned for unknown rules or bad params in
+// `validate` tags, it is a programming error rather than a bad request
+var ErrInvalidValidateTag = errors.New("invalid validate tag")
+
+// ValidationFunc report whether v satisfies the rule with its param, such as "1" for min=1
+type ValidationFunc func(v reflect.Value, param string) bool
+
+// FieldError describe one failed rule, Field is the JSON path such as items[0].name
+type FieldError struct {
+	Field string      `json:"field"`
+	Rule  string      `json:"rule"`
+	Param string      `json:"param,omitempty"`
+	Value interface{} `json:"-"`
+}
+
+func (e FieldError) Error() string {
+	return e.Translate(defaultLanguage)
+}
+
+// Translate render the message of the rule in lang, falling back to the default language
+func (e FieldError) Translate(lang string) string {
+	tpl := lookupTranslation(lang, e.Rule)
+	r := strings.NewReplacer("{field}", e.Field, "{param}", e.Param, "{rule}", e.Rule)
+	return r.Replace(tpl)
+}
+
+type ValidationErrors []FieldError
+
+func (es ValidationErrors) Error() string {
+	msgs := make([]string, 0, len(es))
+	for _, e := range es {
+		msgs = append(msgs, e.Error())
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Translate return the messages in lang keyed by field path
+func (es ValidationErrors) Translate(lang string) map[string]string {
+	ret := make(map[string]string, len(es))
+	for _, e := range es {
+		if _, ok := ret[e.Field]; !ok {
+			ret[e.Field] = e.Translate(lang)
+		}
+	}
+	return ret
+}
+
+const defaultLanguage = "en"
+
+var (
+	validations  = map[string]ValidationFunc{}
+	translations = map[string]map[string]string{}
+	validateLock sync.RWMutex
+
+	// 参数需要是数字（或 time.Duration）的内置规则，被 RegisterValidation 覆盖后移除
+	compareRules = map[string]bool{}
+	// 每个结构体类型的 validate tag 只检查一次
+	tagChecks sync.Map
+)
+
+var (
+	emailRegexp = regexp.MustCompile(`^[a-zA-Z0-9.!#$%&'*+/=?^_{|}~-]+@[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?(?:\.[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)*$`)
+	uuidRegexp  = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+)
+
+func init() {
+	RegisterValidation("required", func(v reflect.Value, _ string) bool { return !v.IsZero() })
+	RegisterValidation("min", func(v reflect.Value, p string) bool {
+		return compareRule(v, p, func(a, b float64) bool { return a >= b })
+	})
+	RegisterValidation("max", func(v reflect.Value, p string) bool {
+		return compareRule(v, p, func(a, b float64) bool { return a <= b })
+	})
+	RegisterValidation("len", func(v reflect.Value, p string) bool {
+		return compareRule(v, p, func(a, b float64) bool { return a == b })
+	})
+	RegisterValidation("gt", func(v reflect.Value, p string) bool {
+		return compareRule(v, p, func(a, b float64) bool { return a > b })
+	})
+	RegisterValidation("gte", func(v reflect.Value, p string) bool {
+		return compareRule(v, p, func(a, b float64) bool { return a >= b })
+	})
+	RegisterValidation("lt", func(v reflect.Value, p string) bool {
+		return compareRule(v, p, func(a, b float64) bool { return a < b })
+	})
+	RegisterValidation("lte", func(v reflect.Value, p string) bool {
+		return compareRule(v, p, func(a, b float64) bool { return a <= b })
+	})
+	RegisterValidation("oneof", func(v reflect.Value, p string) bool {
+		s, ok := scalarString(v)
+		if !ok {
+			return false
+		}
+		for _, opt := range strings.Fields(p) {
+			if s == opt {
+				return true
+			}
+		}
+		return false
+	})
+	RegisterValidation("email", func(v reflect.Value, _ string) bool {
+		return v.Kind() == reflect.String && emailRegexp.MatchString(v.String())
+	})
+	RegisterValidation("url", func(v reflect.Value, _ string) bool {
+		if v.Kind() != reflect.String {
+			return false
+		}
+		u, err := url.ParseRequestURI(v.String())
+		return err == nil && u.Scheme != "" && u.Host != ""
+	})
+	RegisterValidation("uuid", func(v reflect.Value, _ string) bool {
+		return v.Kind() == reflect.String && uuidRegexp.MatchString(v.String())
+	})
+
+	for _, name := range []string{"min", "max", "len", "gt", "gte", "lt", "lte"} {
+		compareRules[name] = true
+	}
+
+	RegisterTranslations(defaultLanguage, map[string]string{
+		"":         "{field} failed on the {rule} rule",
+		"required": "{field} is required",
+		"min":      "{field} must be at least {param}",
+		"max":      "{field} must be at most {param}",
+		"len":      "{field} must have length {param}",
+		"gt":       "{field} must be greater than {param}",
+		"gte":      "{field} must be greater than or equal to {param}",
+		"lt":       "{field} must be less than {param}",
+		"lte":      "{field} must be less than or equal to {param}",
+		"oneof":    "{field} must be one of [{param}]",
+		"email":    "{field} must be a valid email address",
+		"url":      "{field} must be a valid URL",
+		"uuid":     "{field} must be a valid UUID",
+	})
+}
+
+// RegisterValidation add or replace the rule used in `validate:"name=param"` tags
+func RegisterValidation(name string, fn ValidationFunc) {
+	validateLock.Lock()
+	defer validateLock.Unlock()
+	validations[name] = fn
+	delete(compareRules, name)
+
+	tagChecks.Range(func(key, _ interface{}) bool {
+		tagChecks.Delete(key)
+		return true
+	})
+}
+
+// RegisterTranslations add messages of lang keyed by rule, the message may use {field}, {param} and {rule},
+// the empty rule is the fallback message
+func RegisterTranslations(lang string, messages map[string]string) {
+	validateLock.Lock()
+	defer validateLock.Unlock()
+	lang = strings.ToLower(lang)
+	if translations[lang] == nil {
+		translations[lang] = map[string]string{}
+	}
+	for rule, msg := range messages {
+		translations[lang][rule] = msg
+	}
+}
+
+func lookupTranslation(lang, rule string) string {
+	validateLock.RLock()
+	defer validateLock.RUnlock()
+	lang = strings.ToLower(lang)
+	for _, l := range []string{lang, strings.SplitN(lang, "-", 2)[0], defaultLanguage} {
+		if msgs, ok := translations[l]; ok {
+			if msg, ok := msgs[rule]; ok {
+				return msg
+			}
+			if msg, ok := msgs[""]; ok && l != defaultLanguage {
+				return msg
+			}
+		}
+	}
+	return translations[defaultLanguage][""]
+}
+
+// Validate check obj against its `validate` tags, failed rules are returned as ValidationErrors
+// and malformed tags as an error wrapping ErrInvalidValidateTag
+func Validate(obj interface{}) error {
+	rv := reflect.ValueOf(obj)
+	var errs ValidationErrors
+	if err := validateValue(rv, "", &errs); err != nil {
+		return err
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// Validate check obj against its `validate` tags, translate ValidationErrors for the client
+// with ctx.Language()
+func (ctx *Context) Validate(obj interface{}) error {
+	return Validate(obj)
+}
+
+// Language return the first language of the Accept-Language header
+func (ctx *Context) Language() string {
+	if ctx.request == nil {
+		return defaultLanguage
+	}
+	accept := ctx.request.Header.Get("Accept-Language")
+	lang := strings.TrimSpace(strings.SplitN(strings.SplitN(accept, ",", 2)[0], ";", 2)[0])
+	if lang == "" || lang == "*" {
+		return defaultLanguage
+	}
+	return lang
+}
+
+func validateValue(rv reflect.Value, path string, errs *ValidationErrors) error {
+	for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+
+	switch rv.Kind() {
+	case reflect.Struct:
+		if rv.Type() == timeType {
+			return nil
+		}
+		rt := rv.Type()
+		if err := checkValidateTags(rt); err != nil {
+			return err
+		}
+		for i := 0; i < rt.NumField(); i++ {
+			field := rt.Field(i)
+			if field.PkgPath != "" && !field.Anonymous {
+				continue
+			}
+			fv := rv.Field(i)
+			fieldPath := path
+			if !field.Anonymous {
+				fieldPath = joinPath(path, jsonFieldName(field))
+			}
+			if !validateField(fv, field.Tag.Get(tagValidate), fieldPath, errs) {
+				continue
+			}
+			if err := validateValue(fv, fieldPath, errs); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			if err := validateValue(rv.Index(i), fmt.Sprintf("%s[%d]", path, i), errs); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		iter := rv.MapRange()
+		for iter.Next() {
+			if err := validateValue(iter.Value(), fmt.Sprintf("%s[%s]", path, mapKeyString(iter.Key())), errs); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// checkValidateTags report unknown rules and bad params in the `validate` tags of rt
+func checkValidateTags(rt reflect.Type) error {
+	if err, ok := tagChecks.Load(rt); ok {
+		if err == nil {
+			return nil
+		}
+		return err.(error)
+	}
+
+	var err error
+	validateLock.RLock()
+	for i := 0; i < rt.NumField() && err == nil; i++ {
+		field := rt.Field(i)
+		tag := field.Tag.Get(tagValidate)
+		if tag == "" || tag == "-" {
+			continue
+		}
+		ft := field.Type
+		for ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		for _, rule := range strings.Split(tag, ",") {
+			name, param, _ := strings.Cut(rule, "=")
+			if name == "" || name == "omitempty" {
+				continue
+			}
+			if _, ok := validations[name]; !ok {
+				err = fmt.Errorf("%w: %s.%s: unknown rule %q", ErrInvalidValidateTag, rt, field.Name, name)
+				break
+			}
+			if compareRules[name] && !validCompareParam(ft, param) {
+				err = fmt.Errorf("%w: %s.%s: invalid param %q for %s", ErrInvalidValidateTag, rt, field.Name, param, name)
+				break
+			}
+		}
+	}
+	validateLock.RUnlock()
+
+	tagChecks.Store(rt, err)
+	return err
+}
+
+func validCompareParam(t reflect.Type, param string) bool {
+	if t == durationType {
+		_, err := time.ParseDuration(param)
+		return err == nil
+	}
+	_, err := strconv.ParseFloat(param, 64)
+	return err == nil
+}
+
+// validateField apply the rules of tag, and report whether nested values should be checked too
+func validateField(fv reflect.Value, tag string, path string, errs *ValidationErrors) bool {
+	if tag == "" {
+		return true
+	}
+	if tag == "-" {
+		return false
+	}
+	rules := strings.Split(tag, ",")
+	for _, rule := range rules {
+		if rule == "omitempty" && fv.IsZero() {
+			return false
+		}
+	}
+
+	v := fv
+	for v.Kind() == reflect.Ptr && !v.IsNil() {
+		v = v.Elem()
+	}
+
+	ok := true
+	for _, rule := range rules {
+		name, param := rule, ""
+		if i := strings.Index(rule, "="); i >= 0 {
+			name, param = rule[:i], rule[i+1:]
+		}
+		if name == "" || name == "omitempty" {
+			continue
+		}
+		validateLock.RLock()
+		fn, exist := validations[name]
+		validateLock.RUnlock()
+		// 规则已由 checkValidateTags 检查
+		if !exist {
+			continue
+		}
+		target := v
+		if name == "required" {
+			target = fv
+		} else if v.Kind() == reflect.Ptr {
+			continue
+		}
+		if !fn(target, param) {
+			ok = false
+			var value interface{}
+			if target.IsValid() && target.CanInterface() {
+				value = target.Interface()
+			}
+			*errs = append(*errs, FieldError{Field: path, Rule: name, Param: param, Value: value})
+			if name == "required" {
+				break
+			}
+		}
+	}
+	return ok
+}
+
+// compareRule compare numbers by value, and strings, slices and maps by length
+func compareRule(v reflect.Value, param string, cmp func(a, b float64) bool) bool {
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(param)
+		if err != nil {
+			return false
+		}
+		return cmp(float64(v.Int()), float64(d))
+	}
+
+	p, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		return false
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return cmp(float64(utf8.RuneCountInString(v.String())), p)
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return cmp(float64(v.Len()), p)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return cmp(float64(v.Int()), p)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return cmp(float64(v.Uint()), p)
+	case reflect.Float32, reflect.Float64:
+		return cmp(v.Float(), p)
+	}
+	return false
+}
+
+// scalarString format a string, integer, float or bool by its kind, v may come from an
+// unexported embedded struct where v.Interface() panics
+func scalarString(v reflect.Value) (string, bool) {
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10), true
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'g', -1, v.Type().Bits()), true
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), true
+	}
+	return "", false
+}
+
+func mapKeyString(key reflect.Value) string {
+	if s, ok := scalarString(key); ok {
+		return s
+	}
+	if key.CanInterface() {
+		return fmt.Sprint(key.Interface())
+	}
+	return key.Type().String()
+}
+
+// jsonFieldName use the json tag, then the binding tags, then the field name
+func jsonFieldName(field reflect.StructField) string {
+	for _, tag := range []string{"json", "xml", tagForm, tagQuery, tagPath, tagHeader} {
+		name := strings.Split(field.Tag.Get(tag), ",")[0]
+		if name != "" && name != "-" {
+			return name
+		}
+	}
+	return field.Name
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
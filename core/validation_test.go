@@ -0,0 +1,38 @@
+package core
+
+import (
+	"errors"
+	"testing"
+)
+
+type validationStatus string
+
+type validationPaging struct {
+	Sort string `validate:"oneof=asc desc"`
+	Size int    `validate:"oneof=10 20 50"`
+}
+
+func TestValidateUnexportedEmbedded(t *testing.T) {
+	type request struct {
+		validationPaging
+		// 非导出的内嵌字段，v.Interface() 会 panic
+		validationStatus `validate:"oneof=draft published"`
+		Name             string `validate:"required"`
+	}
+
+	ok := request{validationPaging: validationPaging{Sort: "asc", Size: 20}, validationStatus: "draft", Name: "a"}
+	if err := Validate(&ok); err != nil {
+		t.Fatalf("got %v", err)
+	}
+
+	bad := request{validationPaging: validationPaging{Sort: "up", Size: 30}, validationStatus: "gone", Name: "a"}
+	var errs ValidationErrors
+	if err := Validate(&bad); !errors.As(err, &errs) || len(errs) != 3 {
+		t.Fatalf("got %v, want three oneof errors", err)
+	}
+	for _, e := range errs {
+		if e.Rule != "oneof" {
+			t.Fatalf("got rule %s, want oneof", e.Rule)
+		}
+	}
+}
@@ -0,0 +1,209 @@
+package core
+
+import (
+	"crypto/sha1"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+var ErrBadHandshake = errors.New("websocket: bad handshake")
+
+const (
+	websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+	defaultMaxMessageSize = 1 << 20
+)
+
+// UpgradeConfig configure the websocket handshake and the resulting connection
+type UpgradeConfig struct {
+	// CheckOrigin report whether the Origin of the request is allowed, by default a request
+	// without Origin or with an Origin whose host equals the Host header
+	CheckOrigin func(r *http.Request) bool
+	// 服务端支持的子协议，按优先级排列
+	Subprotocols []string
+	// 单条消息（解压后）的最大字节数，默认 1MB
+	MaxMessageSize int64
+	// 客户端提供时启用 permessage-deflate
+	EnableCompression bool
+}
+
+// IsWebSocket report whether the request asks for a websocket upgrade
+func (ctx *Context) IsWebSocket() bool {
+	if ctx.request == nil {
+		return false
+	}
+	return headerContainsToken(ctx.request.Header, "Connection", "upgrade") &&
+		headerContainsToken(ctx.request.Header, "Upgrade", "websocket")
+}
+
+// Upgrade switch the connection to the websocket protocol with the default config
+func (ctx *Context) Upgrade() (*WebSocketConn, error) {
+	return ctx.UpgradeWithConfig(UpgradeConfig{})
+}
+
+// UpgradeWithConfig switch the connection to the websocket protocol, a failed handshake is
+// answered here and ErrBadHandshake returned, the response is finished in both cases
+func (ctx *Context) UpgradeWithConfig(config UpgradeConfig) (*WebSocketConn, error) {
+	r := ctx.request
+	if r == nil {
+		return nil, errors.New("ctx.request empty")
+	}
+	if config.MaxMessageSize <= 0 {
+		config.MaxMessageSize = defaultMaxMessageSize
+	}
+	if config.CheckOrigin == nil {
+		config.CheckOrigin = sameOrigin
+	}
+
+	if r.Method != http.MethodGet || !ctx.IsWebSocket() {
+		return nil, ctx.rejectUpgrade(http.StatusBadRequest, "not a websocket handshake")
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		ctx.response.Header().Set("Sec-WebSocket-Version", "13")
+		return nil, ctx.rejectUpgrade(http.StatusUpgradeRequired, "unsupported websocket version")
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if b, err := base64.StdEncoding.DecodeString(key); err != nil || len(b) != 16 {
+		return nil, ctx.rejectUpgrade(http.StatusBadRequest, "invalid Sec-WebSocket-Key")
+	}
+	if !config.CheckOrigin(r) {
+		return nil, ctx.rejectUpgrade(http.StatusForbidden, "origin not allowed")
+	}
+
+	subprotocol := selectSubprotocol(r, config.Subprotocols)
+	compress := config.EnableCompression && acceptDeflate(r)
+
+	ctx.WriteMutex().Lock()
+	defer ctx.WriteMutex().Unlock()
+	if ctx.HasStopped() {
+		return nil, ErrResponseStopped
+	}
+	if ctx.response.Committed() {
+		return nil, errors.New("websocket: response already committed")
+	}
+
+	netConn, brw, err := ctx.response.Hijack()
+	if err != nil {
+		return nil, err
+	}
+	// 连接已被接管，之后的渲染都不应再写入
+	ctx.SetHasStopped()
+
+	var b strings.Builder
+	b.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n")
+	b.WriteString("Sec-WebSocket-Accept: " + acceptKey(key) + "\r\n")
+	if subprotocol != "" {
+		b.WriteString("Sec-WebSocket-Protocol: " + subprotocol + "\r\n")
+	}
+	if compress {
+		b.WriteString("Sec-WebSocket-Extensions: permessage-deflate; server_no_context_takeover; client_no_context_takeover\r\n")
+	}
+	b.WriteString("\r\n")
+
+	if _, err := brw.WriteString(b.String()); err != nil {
+		netConn.Close()
+		return nil, err
+	}
+	if err := brw.Flush(); err != nil {
+		netConn.Close()
+		return nil, err
+	}
+
+	return newWebSocketConn(netConn, brw, subprotocol, compress, config.MaxMessageSize), nil
+}
+
+func (ctx *Context) rejectUpgrade(code int, msg string) error {
+	ctx.WriteMutex().Lock()
+	defer ctx.WriteMutex().Unlock()
+	if ctx.HasStopped() {
+		return ErrBadHandshake
+	}
+
+	ctx.response.WriteHeader(code)
+	ctx.setHeader("Content-Type", "text/plain")
+	ctx.response.Write([]byte(msg))
+	// 握手失败的响应已写出，避免错误处理再次响应
+	ctx.SetHasStopped()
+	return ErrBadHandshake
+}
+
+func acceptKey(key string) string {
+	h := sha1.New()
+	h.Write([]byte(key + websocketGUID))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(u.Host, r.Host)
+}
+
+func selectSubprotocol(r *http.Request, supported []string) string {
+	for _, offer := range headerTokens(r.Header, "Sec-WebSocket-Protocol") {
+		for _, p := range supported {
+			if offer == p {
+				return p
+			}
+		}
+	}
+	return ""
+}
+
+// acceptDeflate report whether the client offers permessage-deflate with parameters the server
+// can honour, the server always answers with no context takeover on both sides
+func acceptDeflate(r *http.Request) bool {
+	for _, ext := range headerTokens(r.Header, "Sec-WebSocket-Extensions") {
+		params := strings.Split(ext, ";")
+		if strings.TrimSpace(params[0]) != "permessage-deflate" {
+			continue
+		}
+		ok := true
+		for _, p := range params[1:] {
+			name, value, _ := strings.Cut(strings.TrimSpace(p), "=")
+			switch name {
+			case "server_no_context_takeover", "client_no_context_takeover", "client_max_window_bits":
+			case "server_max_window_bits":
+				// compress/flate always uses a 32KB window
+				ok = ok && strings.Trim(value, `"`) == "15"
+			default:
+				ok = false
+			}
+		}
+		if ok {
+			return true
+		}
+	}
+	return false
+}
+
+// headerTokens split the comma separated values of header key
+func headerTokens(h http.Header, key string) []string {
+	var tokens []string
+	for _, v := range h.Values(key) {
+		for _, t := range strings.Split(v, ",") {
+			if t = strings.TrimSpace(t); t != "" {
+				tokens = append(tokens, t)
+			}
+		}
+	}
+	return tokens
+}
+
+func headerContainsToken(h http.Header, key, token string) bool {
+	for _, t := range headerTokens(h, key) {
+		if strings.EqualFold(t, token) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,85 @@
+package core
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"net/http"
+)
+
+var _ http.ResponseWriter = (*responseWriter)(nil)
+var _ http.Flusher = (*responseWriter)(nil)
+
+// responseWriter buffer status code and headers until the first body write or an explicit flush
+type responseWriter struct {
+	http.ResponseWriter
+	status    int
+	size      int
+	committed bool
+}
+
+func newResponseWriter(w http.ResponseWriter) *responseWriter {
+	return &responseWriter{
+		ResponseWriter: w,
+		status:         http.StatusOK,
+	}
+}
+
+func (w *responseWriter) WriteHeader(code int) {
+	if code <= 0 || w.status == code {
+		return
+	}
+	if w.committed {
+		debugPrint("status code %d ignored, response already committed with %d", code, w.status)
+		return
+	}
+	w.status = code
+}
+
+// WriteHeaderNow send the buffered status and headers to the client
+func (w *responseWriter) WriteHeaderNow() {
+	if w.committed {
+		return
+	}
+	w.committed = true
+	w.ResponseWriter.WriteHeader(w.status)
+}
+
+func (w *responseWriter) Write(b []byte) (int, error) {
+	w.WriteHeaderNow()
+	n, err := w.ResponseWriter.Write(b)
+	w.size += n
+	return n, err
+}
+
+func (w *responseWriter) Flush() {
+	w.WriteHeaderNow()
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response writer does not implement http.Hijacker")
+	}
+	w.committed = true
+	return h.Hijack()
+}
+
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *responseWriter) Status() int {
+	return w.status
+}
+
+func (w *responseWriter) Size() int {
+	return w.size
+}
+
+func (w *responseWriter) Committed() bool {
+	return w.committed
+}
@@ -0,0 +1,469 @@
+package core
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// websocket message types, the values are the frame opcodes of RFC 6455
+const (
+	TextMessage   = 1
+	BinaryMessage = 2
+	CloseMessage  = 8
+	PingMessage   = 9
+	PongMessage   = 10
+
+	continuationFrame = 0
+)
+
+// websocket close codes
+const (
+	CloseNormalClosure           = 1000
+	CloseGoingAway               = 1001
+	CloseProtocolError           = 1002
+	CloseUnsupportedData         = 1003
+	CloseNoStatusReceived        = 1005
+	CloseAbnormalClosure         = 1006
+	CloseInvalidFramePayloadData = 1007
+	ClosePolicyViolation         = 1008
+	CloseMessageTooBig           = 1009
+	CloseMandatoryExtension      = 1010
+	CloseInternalServerErr       = 1011
+	CloseServiceRestart          = 1012
+	CloseTryAgainLater           = 1013
+	CloseBadGateway              = 1014
+)
+
+var (
+	ErrMessageTooBig = errors.New("websocket: message too big")
+	ErrCloseSent     = errors.New("websocket: close sent")
+)
+
+// 小于该大小的消息不压缩
+const minCompressSize = 256
+
+var flateWriterPool = sync.Pool{New: func() interface{} {
+	w, _ := flate.NewWriter(nil, flate.BestSpeed)
+	return w
+}}
+
+// CloseError is returned by ReadMessage when the peer closes the connection
+type CloseError struct {
+	Code int
+	Text string
+}
+
+func (e *CloseError) Error() string {
+	return "websocket: close " + strconv.Itoa(e.Code) + " " + e.Text
+}
+
+// WebSocketConn is an upgraded connection, one goroutine may read while others write
+type WebSocketConn struct {
+	conn net.Conn
+	br   *bufio.Reader
+
+	writeMutex sync.Mutex
+	bw         *bufio.Writer
+	closeSent  bool
+
+	subprotocol    string
+	compress       bool
+	maxMessageSize int64
+
+	pingHandler func(data string) error
+	pongHandler func(data string) error
+	readErr     error
+}
+
+func newWebSocketConn(conn net.Conn, brw *bufio.ReadWriter, subprotocol string, compress bool, maxMessageSize int64) *WebSocketConn {
+	c := &WebSocketConn{
+		conn:           conn,
+		br:             brw.Reader,
+		bw:             brw.Writer,
+		subprotocol:    subprotocol,
+		compress:       compress,
+		maxMessageSize: maxMessageSize,
+	}
+	c.pingHandler = func(data string) error {
+		err := c.WriteControl(PongMessage, []byte(data))
+		if err == ErrCloseSent {
+			return nil
+		}
+		return err
+	}
+	return c
+}
+
+// Subprotocol return the negotiated subprotocol
+func (c *WebSocketConn) Subprotocol() string {
+	return c.subprotocol
+}
+
+func (c *WebSocketConn) RemoteAddr() net.Addr {
+	return c.conn.RemoteAddr()
+}
+
+func (c *WebSocketConn) SetReadDeadline(t time.Time) error {
+	return c.conn.SetReadDeadline(t)
+}
+
+func (c *WebSocketConn) SetWriteDeadline(t time.Time) error {
+	return c.conn.SetWriteDeadline(t)
+}
+
+// SetPingHandler replace the default ping handler, which answers with a pong
+func (c *WebSocketConn) SetPingHandler(h func(data string) error) {
+	c.pingHandler = h
+}
+
+// SetPongHandler set the handler called for each pong, typically to extend the read deadline
+func (c *WebSocketConn) SetPongHandler(h func(data string) error) {
+	c.pongHandler = h
+}
+
+// ReadMessage return the next text or binary message, control frames are handled on the way;
+// once it fails every later call returns the same error
+func (c *WebSocketConn) ReadMessage() (messageType int, data []byte, err error) {
+	if c.readErr != nil {
+		return 0, nil, c.readErr
+	}
+	messageType, data, err = c.readMessage()
+	if err != nil {
+		c.readErr = err
+	}
+	return messageType, data, err
+}
+
+// ReadJSON read the next message and unmarshal it into obj
+func (c *WebSocketConn) ReadJSON(obj interface{}) error {
+	_, data, err := c.ReadMessage()
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, obj)
+}
+
+func (c *WebSocketConn) readMessage() (int, []byte, error) {
+	var (
+		msgType    int
+		compressed bool
+		buf        []byte
+	)
+	for {
+		f, err := c.readFrame(c.maxMessageSize - int64(len(buf)))
+		if err != nil {
+			return 0, nil, err
+		}
+
+		if f.opcode >= CloseMessage {
+			if err := c.handleControl(f); err != nil {
+				return 0, nil, err
+			}
+			continue
+		}
+
+		switch {
+		case f.opcode == continuationFrame:
+			if msgType == 0 {
+				return 0, nil, c.fail(CloseProtocolError, errors.New("websocket: unexpected continuation frame"))
+			}
+			if f.rsv1 {
+				return 0, nil, c.fail(CloseProtocolError, errors.New("websocket: rsv1 set on continuation frame"))
+			}
+		case f.opcode == TextMessage || f.opcode == BinaryMessage:
+			if msgType != 0 {
+				return 0, nil, c.fail(CloseProtocolError, errors.New("websocket: expected continuation frame"))
+			}
+			msgType, compressed = f.opcode, f.rsv1
+		default:
+			return 0, nil, c.fail(CloseProtocolError, errors.New("websocket: unknown opcode "+strconv.Itoa(f.opcode)))
+		}
+
+		buf = append(buf, f.payload...)
+		if !f.fin {
+			continue
+		}
+
+		if compressed {
+			if buf, err = inflate(buf, c.maxMessageSize); err == ErrMessageTooBig {
+				return 0, nil, c.fail(CloseMessageTooBig, err)
+			} else if err != nil {
+				return 0, nil, c.fail(CloseInvalidFramePayloadData, err)
+			}
+		}
+		if msgType == TextMessage && !utf8.Valid(buf) {
+			return 0, nil, c.fail(CloseInvalidFramePayloadData, errors.New("websocket: invalid utf-8 in text message"))
+		}
+		return msgType, buf, nil
+	}
+}
+
+type wsFrame struct {
+	fin     bool
+	rsv1    bool
+	opcode  int
+	payload []byte
+}
+
+// readFrame read one client frame whose payload is at most limit bytes, control frames aside
+func (c *WebSocketConn) readFrame(limit int64) (*wsFrame, error) {
+	var h [2]byte
+	if _, err := io.ReadFull(c.br, h[:]); err != nil {
+		return nil, err
+	}
+
+	f := &wsFrame{
+		fin:    h[0]&0x80 != 0,
+		rsv1:   h[0]&0x40 != 0,
+		opcode: int(h[0] & 0x0f),
+	}
+	if h[0]&0x30 != 0 || (f.rsv1 && !c.compress) {
+		return nil, c.fail(CloseProtocolError, errors.New("websocket: unexpected reserved bits"))
+	}
+	if h[1]&0x80 == 0 {
+		return nil, c.fail(CloseProtocolError, errors.New("websocket: client frame not masked"))
+	}
+
+	control := f.opcode >= CloseMessage
+	length := int64(h[1] & 0x7f)
+	if control && (!f.fin || f.rsv1 || length > 125) {
+		return nil, c.fail(CloseProtocolError, errors.New("websocket: invalid control frame"))
+	}
+
+	switch length {
+	case 126:
+		var b [2]byte
+		if _, err := io.ReadFull(c.br, b[:]); err != nil {
+			return nil, err
+		}
+		length = int64(binary.BigEndian.Uint16(b[:]))
+	case 127:
+		var b [8]byte
+		if _, err := io.ReadFull(c.br, b[:]); err != nil {
+			return nil, err
+		}
+		length = int64(binary.BigEndian.Uint64(b[:]))
+		if length < 0 {
+			return nil, c.fail(CloseProtocolError, errors.New("websocket: invalid frame length"))
+		}
+	}
+	if !control && length > limit {
+		return nil, c.fail(CloseMessageTooBig, ErrMessageTooBig)
+	}
+
+	var mask [4]byte
+	if _, err := io.ReadFull(c.br, mask[:]); err != nil {
+		return nil, err
+	}
+	f.payload = make([]byte, length)
+	if _, err := io.ReadFull(c.br, f.payload); err != nil {
+		return nil, err
+	}
+	for i := range f.payload {
+		f.payload[i] ^= mask[i%4]
+	}
+	return f, nil
+}
+
+func (c *WebSocketConn) handleControl(f *wsFrame) error {
+	switch f.opcode {
+	case PingMessage:
+		if c.pingHandler != nil {
+			return c.pingHandler(string(f.payload))
+		}
+	case PongMessage:
+		if c.pongHandler != nil {
+			return c.pongHandler(string(f.payload))
+		}
+	case CloseMessage:
+		code, text := CloseNoStatusReceived, ""
+		switch {
+		case len(f.payload) == 1:
+			return c.fail(CloseProtocolError, errors.New("websocket: invalid close payload"))
+		case len(f.payload) >= 2:
+			code = int(binary.BigEndian.Uint16(f.payload))
+			text = string(f.payload[2:])
+			if !validCloseCode(code) || !utf8.ValidString(text) {
+				return c.fail(CloseProtocolError, errors.New("websocket: invalid close payload"))
+			}
+		}
+
+		// 回应关闭帧后断开连接
+		var echo []byte
+		if code != CloseNoStatusReceived {
+			echo = formatClose(code, "")
+		}
+		c.writeFrame(CloseMessage, false, echo)
+		c.conn.Close()
+		return &CloseError{Code: code, Text: text}
+	default:
+		return c.fail(CloseProtocolError, errors.New("websocket: unknown opcode "+strconv.Itoa(f.opcode)))
+	}
+	return nil
+}
+
+// fail close the connection with code after a protocol violation and return err
+func (c *WebSocketConn) fail(code int, err error) error {
+	c.writeFrame(CloseMessage, false, formatClose(code, err.Error()))
+	c.conn.Close()
+	return err
+}
+
+// WriteMessage send a text or binary message, compressed when permessage-deflate was negotiated
+func (c *WebSocketConn) WriteMessage(messageType int, data []byte) error {
+	if messageType != TextMessage && messageType != BinaryMessage {
+		return c.WriteControl(messageType, data)
+	}
+	if c.compress && len(data) >= minCompressSize {
+		b, err := deflate(data)
+		if err != nil {
+			return err
+		}
+		return c.writeFrame(messageType, true, b)
+	}
+	return c.writeFrame(messageType, false, data)
+}
+
+// WriteJSON marshal obj and send it as a text message
+func (c *WebSocketConn) WriteJSON(obj interface{}) error {
+	b, err := json.Marshal(obj)
+	if err != nil {
+		return err
+	}
+	return c.WriteMessage(TextMessage, b)
+}
+
+// WriteControl send a ping, pong or close frame
+func (c *WebSocketConn) WriteControl(messageType int, data []byte) error {
+	if messageType < CloseMessage || messageType > PongMessage {
+		return errors.New("websocket: invalid control message type " + strconv.Itoa(messageType))
+	}
+	if len(data) > 125 {
+		return errors.New("websocket: control frame payload too long")
+	}
+	return c.writeFrame(messageType, false, data)
+}
+
+// Ping send a ping, the pong is passed to the pong handler by ReadMessage
+func (c *WebSocketConn) Ping(data []byte) error {
+	return c.WriteControl(PingMessage, data)
+}
+
+// Close close the connection with a normal closure
+func (c *WebSocketConn) Close() error {
+	return c.CloseWithCode(CloseNormalClosure, "")
+}
+
+// CloseWithCode send a close frame with code and reason, then close the connection
+func (c *WebSocketConn) CloseWithCode(code int, reason string) error {
+	err := c.writeFrame(CloseMessage, false, formatClose(code, reason))
+	if cerr := c.conn.Close(); err == nil || err == ErrCloseSent {
+		err = cerr
+	}
+	return err
+}
+
+func (c *WebSocketConn) writeFrame(opcode int, rsv1 bool, payload []byte) error {
+	c.writeMutex.Lock()
+	defer c.writeMutex.Unlock()
+
+	if c.closeSent {
+		return ErrCloseSent
+	}
+	if opcode == CloseMessage {
+		c.closeSent = true
+	}
+
+	// 服务端帧不加掩码
+	h := make([]byte, 0, 10)
+	b0 := byte(0x80 | opcode)
+	if rsv1 {
+		b0 |= 0x40
+	}
+	h = append(h, b0)
+	switch n := len(payload); {
+	case n <= 125:
+		h = append(h, byte(n))
+	case n <= 0xffff:
+		h = append(h, 126)
+		h = binary.BigEndian.AppendUint16(h, uint16(n))
+	default:
+		h = append(h, 127)
+		h = binary.BigEndian.AppendUint64(h, uint64(n))
+	}
+
+	if _, err := c.bw.Write(h); err != nil {
+		return err
+	}
+	if _, err := c.bw.Write(payload); err != nil {
+		return err
+	}
+	return c.bw.Flush()
+}
+
+func formatClose(code int, text string) []byte {
+	if code == CloseNoStatusReceived {
+		return nil
+	}
+	// 关闭帧负载不能超过 125 字节
+	if len(text) > 123 {
+		text = text[:123]
+		for !utf8.ValidString(text) {
+			text = text[:len(text)-1]
+		}
+	}
+	b := binary.BigEndian.AppendUint16(nil, uint16(code))
+	return append(b, text...)
+}
+
+func validCloseCode(code int) bool {
+	switch {
+	case code >= 1000 && code <= 1003, code >= 1007 && code <= 1014, code >= 3000 && code <= 4999:
+		return true
+	}
+	return false
+}
+
+// deflate compress data as one permessage-deflate message, without the trailing empty block
+func deflate(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w := flateWriterPool.Get().(*flate.Writer)
+	defer flateWriterPool.Put(w)
+
+	w.Reset(&buf)
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Flush(); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte{0, 0, 0xff, 0xff}), nil
+}
+
+// inflate decompress a permessage-deflate message to at most limit bytes
+func inflate(data []byte, limit int64) ([]byte, error) {
+	// 补回去掉的空块，再追加一个结束块让读取正常结束
+	tail := strings.NewReader("\x00\x00\xff\xff\x01\x00\x00\xff\xff")
+	r := flate.NewReader(io.MultiReader(bytes.NewReader(data), tail))
+	defer r.Close()
+
+	b, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(b)) > limit {
+		return nil, ErrMessageTooBig
+	}
+	return b, nil
+}
@@ -0,0 +1,323 @@
+package core
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+type wsTestFrame struct {
+	fin     bool
+	rsv1    bool
+	masked  bool
+	opcode  int
+	payload []byte
+}
+
+// wsTestClient is the client end of a net.Pipe, it collects the frames sent by the server
+type wsTestClient struct {
+	conn   net.Conn
+	frames chan wsTestFrame
+}
+
+func newWSTestPair(t *testing.T, compress bool, maxMessageSize int64) (*WebSocketConn, *wsTestClient) {
+	t.Helper()
+	server, client := net.Pipe()
+	ws := newWebSocketConn(server, bufio.NewReadWriter(bufio.NewReader(server), bufio.NewWriter(server)), "", compress, maxMessageSize)
+	c := &wsTestClient{conn: client, frames: make(chan wsTestFrame, 16)}
+	go c.readLoop()
+	t.Cleanup(func() {
+		client.Close()
+		server.Close()
+	})
+	return ws, c
+}
+
+func (c *wsTestClient) readLoop() {
+	defer close(c.frames)
+	r := bufio.NewReader(c.conn)
+	for {
+		var h [2]byte
+		if _, err := io.ReadFull(r, h[:]); err != nil {
+			return
+		}
+		f := wsTestFrame{
+			fin:    h[0]&0x80 != 0,
+			rsv1:   h[0]&0x40 != 0,
+			masked: h[1]&0x80 != 0,
+			opcode: int(h[0] & 0x0f),
+		}
+		n := int(h[1] & 0x7f)
+		switch n {
+		case 126:
+			var b [2]byte
+			io.ReadFull(r, b[:])
+			n = int(binary.BigEndian.Uint16(b[:]))
+		case 127:
+			var b [8]byte
+			io.ReadFull(r, b[:])
+			n = int(binary.BigEndian.Uint64(b[:]))
+		}
+		f.payload = make([]byte, n)
+		if _, err := io.ReadFull(r, f.payload); err != nil {
+			return
+		}
+		c.frames <- f
+	}
+}
+
+// send write the frames from another goroutine, net.Pipe blocks until the server reads them
+func (c *wsTestClient) send(frames ...[]byte) {
+	go c.conn.Write(bytes.Join(frames, nil))
+}
+
+func (c *wsTestClient) next(t *testing.T) wsTestFrame {
+	t.Helper()
+	select {
+	case f, ok := <-c.frames:
+		if !ok {
+			t.Fatal("connection closed before the expected frame")
+		}
+		return f
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for a frame")
+	}
+	return wsTestFrame{}
+}
+
+func (c *wsTestClient) expectClose(t *testing.T, code int) {
+	t.Helper()
+	f := c.next(t)
+	if f.opcode != CloseMessage || len(f.payload) < 2 {
+		t.Fatalf("got opcode %d payload %q, want close", f.opcode, f.payload)
+	}
+	if got := int(binary.BigEndian.Uint16(f.payload)); got != code {
+		t.Fatalf("got close code %d, want %d", got, code)
+	}
+}
+
+// clientFrame build a masked client frame
+func clientFrame(opcode int, fin, rsv1 bool, payload []byte) []byte {
+	b0 := byte(opcode)
+	if fin {
+		b0 |= 0x80
+	}
+	if rsv1 {
+		b0 |= 0x40
+	}
+	b := []byte{b0}
+	switch n := len(payload); {
+	case n <= 125:
+		b = append(b, 0x80|byte(n))
+	case n <= 0xffff:
+		b = append(b, 0x80|126)
+		b = binary.BigEndian.AppendUint16(b, uint16(n))
+	default:
+		b = append(b, 0x80|127)
+		b = binary.BigEndian.AppendUint64(b, uint64(n))
+	}
+	mask := []byte{0x37, 0xfa, 0x21, 0x3d}
+	b = append(b, mask...)
+	for i, c := range payload {
+		b = append(b, c^mask[i%4])
+	}
+	return b
+}
+
+func TestWebSocketMasking(t *testing.T) {
+	ws, client := newWSTestPair(t, false, 1024)
+
+	client.send(clientFrame(TextMessage, true, false, []byte("hello")))
+	typ, data, err := ws.ReadMessage()
+	if err != nil || typ != TextMessage || string(data) != "hello" {
+		t.Fatalf("got %d %q %v", typ, data, err)
+	}
+
+	go ws.WriteMessage(BinaryMessage, []byte("hi"))
+	f := client.next(t)
+	if f.masked || !f.fin || f.opcode != BinaryMessage || string(f.payload) != "hi" {
+		t.Fatalf("unexpected server frame %+v", f)
+	}
+}
+
+func TestWebSocketUnmaskedClientFrame(t *testing.T) {
+	ws, client := newWSTestPair(t, false, 1024)
+
+	client.send([]byte{0x81, 0x02, 'h', 'i'})
+	if _, _, err := ws.ReadMessage(); err == nil {
+		t.Fatal("expected an error for an unmasked frame")
+	}
+	client.expectClose(t, CloseProtocolError)
+}
+
+func TestWebSocketFragmentedWithPing(t *testing.T) {
+	ws, client := newWSTestPair(t, false, 1024)
+
+	client.send(
+		clientFrame(TextMessage, false, false, []byte("Hel")),
+		clientFrame(PingMessage, true, false, []byte("p1")),
+		clientFrame(continuationFrame, false, false, []byte("l")),
+		clientFrame(continuationFrame, true, false, []byte("o")),
+	)
+	typ, data, err := ws.ReadMessage()
+	if err != nil || typ != TextMessage || string(data) != "Hello" {
+		t.Fatalf("got %d %q %v", typ, data, err)
+	}
+	if f := client.next(t); f.opcode != PongMessage || string(f.payload) != "p1" {
+		t.Fatalf("got %+v, want pong p1", f)
+	}
+}
+
+func TestWebSocketUnexpectedContinuation(t *testing.T) {
+	ws, client := newWSTestPair(t, false, 1024)
+
+	client.send(clientFrame(continuationFrame, true, false, []byte("x")))
+	if _, _, err := ws.ReadMessage(); err == nil {
+		t.Fatal("expected an error for a continuation without a message")
+	}
+	client.expectClose(t, CloseProtocolError)
+}
+
+func TestWebSocketMessageTooBig(t *testing.T) {
+	ws, client := newWSTestPair(t, false, 10)
+
+	client.send(
+		clientFrame(BinaryMessage, false, false, []byte("123456")),
+		clientFrame(continuationFrame, true, false, []byte("789012")),
+	)
+	if _, _, err := ws.ReadMessage(); !errors.Is(err, ErrMessageTooBig) {
+		t.Fatalf("got %v, want %v", err, ErrMessageTooBig)
+	}
+	client.expectClose(t, CloseMessageTooBig)
+}
+
+func TestWebSocketInvalidUTF8(t *testing.T) {
+	ws, client := newWSTestPair(t, false, 1024)
+
+	client.send(clientFrame(TextMessage, true, false, []byte{0xff, 0xfe, 'a'}))
+	if _, _, err := ws.ReadMessage(); err == nil {
+		t.Fatal("expected an error for invalid utf-8")
+	}
+	client.expectClose(t, CloseInvalidFramePayloadData)
+}
+
+func TestWebSocketDeflateRoundTrip(t *testing.T) {
+	ws, client := newWSTestPair(t, true, 1<<20)
+
+	msg := strings.Repeat("compress me ", 100)
+	compressed, err := deflate([]byte(msg))
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.send(clientFrame(TextMessage, true, true, compressed))
+	typ, data, err := ws.ReadMessage()
+	if err != nil || typ != TextMessage || string(data) != msg {
+		t.Fatalf("got %d %d bytes %v", typ, len(data), err)
+	}
+
+	go ws.WriteMessage(TextMessage, []byte(msg))
+	f := client.next(t)
+	if !f.rsv1 || len(f.payload) >= len(msg) {
+		t.Fatalf("expected a compressed frame, got rsv1=%v with %d bytes", f.rsv1, len(f.payload))
+	}
+	out, err := inflate(f.payload, 1<<20)
+	if err != nil || string(out) != msg {
+		t.Fatalf("inflate: %v", err)
+	}
+
+	// 小消息不压缩
+	go ws.WriteMessage(TextMessage, []byte("tiny"))
+	if f := client.next(t); f.rsv1 || string(f.payload) != "tiny" {
+		t.Fatalf("got %+v, want an uncompressed frame", f)
+	}
+}
+
+func TestWebSocketDeflateTooBig(t *testing.T) {
+	ws, client := newWSTestPair(t, true, 100)
+
+	compressed, _ := deflate(make([]byte, 1000))
+	client.send(clientFrame(BinaryMessage, true, true, compressed))
+	if _, _, err := ws.ReadMessage(); !errors.Is(err, ErrMessageTooBig) {
+		t.Fatalf("got %v, want %v", err, ErrMessageTooBig)
+	}
+	client.expectClose(t, CloseMessageTooBig)
+}
+
+func TestWebSocketRSV1WithoutDeflate(t *testing.T) {
+	ws, client := newWSTestPair(t, false, 1024)
+
+	client.send(clientFrame(TextMessage, true, true, []byte("x")))
+	if _, _, err := ws.ReadMessage(); err == nil {
+		t.Fatal("expected an error for rsv1 without permessage-deflate")
+	}
+	client.expectClose(t, CloseProtocolError)
+}
+
+func TestWebSocketPeerClose(t *testing.T) {
+	ws, client := newWSTestPair(t, false, 1024)
+
+	payload := append(binary.BigEndian.AppendUint16(nil, CloseServiceRestart), "restart"...)
+	client.send(clientFrame(CloseMessage, true, false, payload))
+	_, _, err := ws.ReadMessage()
+	var closeErr *CloseError
+	if !errors.As(err, &closeErr) || closeErr.Code != CloseServiceRestart || closeErr.Text != "restart" {
+		t.Fatalf("got %v, want close 1012", err)
+	}
+	client.expectClose(t, CloseServiceRestart)
+
+	if err := ws.WriteMessage(TextMessage, []byte("late")); err != ErrCloseSent {
+		t.Fatalf("got %v, want %v", err, ErrCloseSent)
+	}
+}
+
+func TestWebSocketInvalidCloseCode(t *testing.T) {
+	ws, client := newWSTestPair(t, false, 1024)
+
+	client.send(clientFrame(CloseMessage, true, false, binary.BigEndian.AppendUint16(nil, CloseNoStatusReceived)))
+	if _, _, err := ws.ReadMessage(); err == nil {
+		t.Fatal("expected an error for close code 1005 on the wire")
+	}
+	client.expectClose(t, CloseProtocolError)
+}
+
+func TestValidCloseCode(t *testing.T) {
+	cases := map[int]bool{
+		999: false, 1000: true, 1003: true, 1004: false, 1005: false, 1006: false,
+		1007: true, 1011: true, 1012: true, 1013: true, 1014: true, 1015: false,
+		2999: false, 3000: true, 4999: true, 5000: false,
+	}
+	for code, want := range cases {
+		if got := validCloseCode(code); got != want {
+			t.Errorf("validCloseCode(%d) = %v, want %v", code, got, want)
+		}
+	}
+}
+
+func TestAcceptDeflate(t *testing.T) {
+	cases := map[string]bool{
+		"permessage-deflate":                         true,
+		"permessage-deflate; client_max_window_bits": true,
+		"permessage-deflate; server_no_context_takeover; client_no_context_takeover": true,
+		`permessage-deflate; server_max_window_bits="15"`:                            true,
+		"permessage-deflate; server_max_window_bits=10":                              false,
+		"permessage-deflate; foo":                                                    false,
+		"permessage-deflate; foo; server_max_window_bits=15":                         false,
+		"permessage-deflate; server_max_window_bits=15; foo":                         false,
+		"permessage-deflate; foo, permessage-deflate":                                true,
+		"x-webkit-deflate-frame":                                                     false,
+	}
+	for offer, want := range cases {
+		r, _ := http.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("Sec-WebSocket-Extensions", offer)
+		if got := acceptDeflate(r); got != want {
+			t.Errorf("acceptDeflate(%q) = %v, want %v", offer, got, want)
+		}
+	}
+}
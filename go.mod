@@ -1,5 +1,9 @@
 module github.com/betNevS/easyweb
 
-go 1.17
+go 1.19
 
-require github.com/spf13/cast v1.4.1
+require (
+	github.com/spf13/cast v1.4.1
+	google.golang.org/protobuf v1.33.0
+	gopkg.in/yaml.v3 v3.0.1
+)
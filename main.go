@@ -1,18 +1,14 @@
 package main
 
 import (
-	"github.com/betNevS/easyweb/core"
 	"log"
-	"net/http"
+
+	"github.com/betNevS/easyweb/core"
 )
 
 func main() {
 	core := core.New()
 	RegisterRouter(core)
-	s := &http.Server{
-		Handler: core,
-		Addr:    ":8080",
-	}
 
-	log.Fatal(s.ListenAndServe())
+	log.Fatal(core.Run(":8080"))
 }